@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ApprovalPolicy decides whose submissions wait in a channel's approval queue.
+type ApprovalPolicy string
+
+const (
+	// ApprovalOff puts every submission live immediately.
+	ApprovalOff ApprovalPolicy = "off"
+	// ApprovalNonMods queues submissions from anyone but moderators and the broadcaster.
+	ApprovalNonMods ApprovalPolicy = "non-mods"
+	// ApprovalNonVIPs also puts VIPs' submissions live immediately.
+	ApprovalNonVIPs ApprovalPolicy = "non-vips"
+	// ApprovalNonSubs queues submissions only from viewers who are not subscribers or above.
+	ApprovalNonSubs ApprovalPolicy = "non-subs"
+)
+
+// parseApprovalPolicy validates a configured approval policy; empty means off.
+func parseApprovalPolicy(name string) (ApprovalPolicy, error) {
+	switch policy := ApprovalPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "", ApprovalOff, "none":
+		return ApprovalOff, nil
+	case ApprovalNonMods, "nonmods", "viewers":
+		return ApprovalNonMods, nil
+	case ApprovalNonVIPs, "nonvips":
+		return ApprovalNonVIPs, nil
+	case ApprovalNonSubs, "nonsubs":
+		return ApprovalNonSubs, nil
+	default:
+		return ApprovalOff, fmt.Errorf("unknown approval policy %q (use off, non-mods, non-vips or non-subs)", name)
+	}
+}
+
+// Requires reports whether a submission from a sender with the given role must be approved before
+// it goes live.
+func (p ApprovalPolicy) Requires(role Role) bool {
+	switch p {
+	case ApprovalNonMods:
+		return role < RoleModerator
+	case ApprovalNonVIPs:
+		return role < RoleVIP
+	case ApprovalNonSubs:
+		return role < RoleSubscriber
+	default:
+		return false
+	}
+}
+
+// Statuses of a PendingQuote.
+const (
+	PendingWaiting  = "pending"
+	PendingApproved = "approved"
+	PendingRejected = "rejected"
+)
+
+// PendingQuote is a submission in a channel's approval queue. ID numbers submissions across all
+// channels; QuoteID is the quote number it was given once approved.
+type PendingQuote struct {
+	ID          int       `json:"id"`
+	Channel     string    `json:"channel"`
+	Text        string    `json:"text"`
+	Author      string    `json:"author"`
+	Tags        []string  `json:"tags,omitempty"`
+	Game        string    `json:"game,omitempty"`
+	StreamTitle string    `json:"stream_title,omitempty"`
+	SubmittedBy string    `json:"submitted_by,omitempty"`
+	SubmitterID string    `json:"submitter_id,omitempty"`
+	Platform    string    `json:"platform,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `json:"status"`
+	ReviewedBy  string    `json:"reviewed_by,omitempty"`
+	ReviewedAt  time.Time `json:"reviewed_at,omitzero"`
+	Reason      string    `json:"reason,omitempty"`
+	QuoteID     int       `json:"quote_id,omitempty"`
+}
+
+// newPendingQuote validates nq and returns it as a waiting submission in channel.
+func newPendingQuote(channel string, nq NewQuote) (PendingQuote, error) {
+	text, author, tags, err := validateNewQuote(nq)
+	if err != nil {
+		return PendingQuote{}, err
+	}
+	return PendingQuote{
+		Channel:     normalizeChannel(channel),
+		Text:        text,
+		Author:      author,
+		Tags:        tags,
+		Game:        strings.TrimSpace(nq.Game),
+		StreamTitle: strings.TrimSpace(nq.StreamTitle),
+		SubmittedBy: strings.TrimSpace(nq.Actor),
+		SubmitterID: strings.TrimSpace(nq.ActorID),
+		Platform:    strings.TrimSpace(nq.Platform),
+		CreatedAt:   time.Now().UTC(),
+		Status:      PendingWaiting,
+	}, nil
+}
+
+// newQuote returns the quote to add when p is approved.
+func (p PendingQuote) newQuote() NewQuote {
+	return NewQuote{
+		Text:        p.Text,
+		Author:      p.Author,
+		Actor:       p.SubmittedBy,
+		ActorID:     p.SubmitterID,
+		Platform:    p.Platform,
+		Tags:        p.Tags,
+		Game:        p.Game,
+		StreamTitle: p.StreamTitle,
+	}
+}
+
+// approvalRevision records who approved a quote, right after its add revision.
+func approvalRevision(q Quote, actor string, pendingID int) Revision {
+	rev := snapshotRevision(q, actor, RevisionApprove)
+	rev.Note = fmt.Sprintf("approved pending #%d", pendingID)
+	return rev
+}
+
+const pendingColumns = "id, channel, text, author, tags, game, stream_title, submitted_by, submitter_id, platform, created_at, status, reviewed_by, reviewed_at, reason, quote_id"
+
+func scanPending(scanner rowScanner) (PendingQuote, error) {
+	var p PendingQuote
+	var tags, created string
+	var reviewedAt sql.NullString
+	var quoteID sql.NullInt64
+	if err := scanner.Scan(&p.ID, &p.Channel, &p.Text, &p.Author, &tags, &p.Game, &p.StreamTitle, &p.SubmittedBy, &p.SubmitterID, &p.Platform, &created, &p.Status, &p.ReviewedBy, &reviewedAt, &p.Reason, &quoteID); err != nil {
+		return PendingQuote{}, err
+	}
+	p.Tags = strings.Fields(tags)
+	var err error
+	if p.CreatedAt, err = parseSQLiteTime(created); err != nil {
+		return PendingQuote{}, err
+	}
+	if reviewedAt.Valid {
+		if p.ReviewedAt, err = parseSQLiteTime(reviewedAt.String); err != nil {
+			return PendingQuote{}, err
+		}
+	}
+	p.QuoteID = int(quoteID.Int64)
+	return p, nil
+}
+
+// Submit puts nq in channel's approval queue instead of adding it. Like Add, it fails with a
+// *DuplicateQuoteError when the quote is already live or already waiting.
+func (s *QuoteStore) Submit(ctx context.Context, channel string, nq NewQuote) (PendingQuote, error) {
+	p, err := newPendingQuote(channel, nq)
+	if err != nil {
+		return PendingQuote{}, err
+	}
+	fingerprint := quoteFingerprint(p.Text)
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := duplicateTx(ctx, tx, p.Channel, fingerprint); err != nil {
+			return err
+		}
+		if fingerprint != "" {
+			var existing int
+			err := tx.QueryRowContext(ctx, "SELECT id FROM pending_quotes WHERE channel = ? AND status = ? AND fingerprint = ? ORDER BY id LIMIT 1", p.Channel, PendingWaiting, fingerprint).Scan(&existing)
+			switch {
+			case err == nil:
+				return &DuplicateQuoteError{ExistingID: existing, Pending: true}
+			case !errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("checking for duplicates: %w", err)
+			}
+		}
+		res, err := tx.ExecContext(ctx, `INSERT INTO pending_quotes(channel, text, author, tags, game, stream_title, submitted_by, submitter_id, platform, fingerprint, created_at, status)
+                VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.Channel, p.Text, p.Author, strings.Join(p.Tags, " "), p.Game, p.StreamTitle, p.SubmittedBy, p.SubmitterID, p.Platform, fingerprint, formatSQLiteTime(p.CreatedAt), p.Status)
+		if err != nil {
+			return fmt.Errorf("queueing quote: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		p.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return PendingQuote{}, err
+	}
+	return p, nil
+}
+
+// Pending returns up to limit submissions waiting in channel's queue, oldest first, and how many
+// are waiting in total. A limit of zero or less returns all of them. It returns ErrNoQuotes when
+// the queue is empty.
+func (s *QuoteStore) Pending(ctx context.Context, channel string, limit int) ([]PendingQuote, int, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	channel = normalizeChannel(channel)
+	var total int
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM pending_quotes WHERE channel = ? AND status = ?", channel, PendingWaiting).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting pending quotes: %w", err)
+	}
+	if total == 0 {
+		return nil, 0, ErrNoQuotes
+	}
+	rows, err := s.read.QueryContext(ctx, "SELECT "+pendingColumns+" FROM pending_quotes WHERE channel = ? AND status = ? ORDER BY id LIMIT ?", channel, PendingWaiting, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing pending quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []PendingQuote
+	for rows.Next() {
+		p, err := scanPending(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scanning pending quote: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating pending quotes: %w", err)
+	}
+	return pending, total, nil
+}
+
+// waitingTx loads a submission that is still waiting in channel's queue.
+func waitingTx(ctx context.Context, tx *sql.Tx, channel string, id int) (PendingQuote, error) {
+	p, err := scanPending(tx.QueryRowContext(ctx, "SELECT "+pendingColumns+" FROM pending_quotes WHERE id = ? AND channel = ? AND status = ?", id, normalizeChannel(channel), PendingWaiting))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PendingQuote{}, fmt.Errorf("no pending quote with id %d found", id)
+		}
+		return PendingQuote{}, fmt.Errorf("fetching pending quote: %w", err)
+	}
+	return p, nil
+}
+
+// Approve adds the waiting submission id in channel as a live quote, recording actor as the
+// approver in its history. Duplicate checks run again, so a submission that has since been added
+// by someone else fails with a *DuplicateQuoteError and stays in the queue.
+func (s *QuoteStore) Approve(ctx context.Context, channel string, id int, actor string) (AddResult, error) {
+	var result AddResult
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		p, err := waitingTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		if result, err = addTx(ctx, tx, channel, p.newQuote()); err != nil {
+			return err
+		}
+		q, err := liveQuoteTx(ctx, tx, channel, result.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, tx, approvalRevision(q, actor, id)); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE pending_quotes SET status = ?, reviewed_by = ?, reviewed_at = ?, quote_id = ? WHERE id = ?",
+			PendingApproved, actor, formatSQLiteTime(time.Now()), result.ID, id); err != nil {
+			return fmt.Errorf("updating pending quote: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return AddResult{}, err
+	}
+	return result, nil
+}
+
+// Reject removes the waiting submission id from channel's queue, recording actor and the optional
+// reason.
+func (s *QuoteStore) Reject(ctx context.Context, channel string, id int, actor, reason string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := waitingTx(ctx, tx, channel, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE pending_quotes SET status = ?, reviewed_by = ?, reviewed_at = ?, reason = ? WHERE id = ?",
+			PendingRejected, actor, formatSQLiteTime(time.Now()), strings.TrimSpace(reason), id); err != nil {
+			return fmt.Errorf("updating pending quote: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup defaults used when the config leaves them unset.
+const (
+	defaultBackupIntervalHours = 24
+	defaultBackupKeep          = 7
+	backupDirName              = "backups"
+	backupTimeLayout           = "20060102-150405"
+)
+
+// BackupSettings controls the scheduled backups of a SQLite store.
+type BackupSettings struct {
+	// Dir is where snapshots are written.
+	Dir string
+	// Interval is the time between scheduled snapshots; zero disables the schedule.
+	Interval time.Duration
+	// Keep is how many snapshots are kept; older ones are removed after each backup.
+	Keep int
+}
+
+// BackupInfo describes a snapshot file.
+type BackupInfo struct {
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// Name returns the snapshot's file name.
+func (b BackupInfo) Name() string {
+	return filepath.Base(b.Path)
+}
+
+// ErrBackupCorrupt is returned when a snapshot fails SQLite's integrity check.
+var ErrBackupCorrupt = errors.New("backup failed integrity check")
+
+// Backup writes a consistent snapshot of the database to a new timestamped file in dir using
+// VACUUM INTO, which is safe while the bot keeps writing, and verifies the copy with
+// PRAGMA integrity_check. A snapshot that fails the check is removed.
+func (s *QuoteStore) Backup(ctx context.Context, dir string) (BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return BackupInfo{}, fmt.Errorf("creating backup directory: %w", err)
+	}
+	now := time.Now()
+	path := filepath.Join(dir, backupFileName(now))
+	if _, err := os.Stat(path); err == nil {
+		return BackupInfo{}, fmt.Errorf("backup %s already exists", filepath.Base(path))
+	}
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return BackupInfo{}, fmt.Errorf("writing backup: %w", err)
+	}
+	if err := verifyBackup(ctx, path); err != nil {
+		os.Remove(path)
+		return BackupInfo{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return BackupInfo{}, fmt.Errorf("reading backup: %w", err)
+	}
+	return BackupInfo{Path: path, CreatedAt: now, Size: stat.Size()}, nil
+}
+
+// RestoreBackup replaces every quote, tag, revision and counter in the database with the contents
+// of the snapshot at path. The snapshot is checked and migrated to the current schema on a
+// temporary copy first, and the switch happens in one transaction, so the store stays usable and a
+// failed restore changes nothing.
+func (s *QuoteStore) RestoreBackup(ctx context.Context, path string) error {
+	if err := verifyBackup(ctx, path); err != nil {
+		return err
+	}
+	staging, err := stageBackup(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staging)
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("opening connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS backup", staging); err != nil {
+		return fmt.Errorf("attaching backup: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE backup")
+
+	tables, err := restorableTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%q", table)); err != nil {
+			return fmt.Errorf("clearing %s: %w", table, err)
+		}
+		list := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%q(%s) SELECT %s FROM backup.%q", table, list, list, table)); err != nil {
+			return fmt.Errorf("restoring %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO quotes_fts(quotes_fts) VALUES ('rebuild')"); err != nil {
+		return fmt.Errorf("rebuilding search index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// restorableTables lists the tables whose rows RestoreBackup copies: everything except the schema
+// bookkeeping and the full-text index, which is rebuilt from quotes afterwards.
+func restorableTables(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM main.sqlite_master
+                WHERE type = 'table' AND name NOT LIKE 'quotes_fts%' AND name <> 'schema_migrations'
+                AND (name NOT LIKE 'sqlite_%' OR name = 'sqlite_sequence')
+                ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("listing tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// tableColumns returns the quoted column names of a table in the main database.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?, 'main')", table)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+		}
+		columns = append(columns, fmt.Sprintf("%q", name))
+	}
+	return columns, rows.Err()
+}
+
+// stageBackup copies the snapshot at path to a temporary file and migrates the copy to the current
+// schema, leaving the snapshot itself untouched. It returns the temporary file's path.
+func stageBackup(ctx context.Context, path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening backup: %w", err)
+	}
+	defer src.Close()
+	dst, err := os.CreateTemp(filepath.Dir(path), ".restore-*.db")
+	if err != nil {
+		return "", fmt.Errorf("staging backup: %w", err)
+	}
+	staging := dst.Name()
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(staging)
+		return "", fmt.Errorf("staging backup: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(staging)
+		return "", fmt.Errorf("staging backup: %w", err)
+	}
+	// The rollback journal keeps the staged copy a single file that can be attached and removed.
+	store, err := NewQuoteStore(ctx, staging, SQLiteOptions{JournalMode: journalModeDelete})
+	if err != nil {
+		os.Remove(staging)
+		return "", fmt.Errorf("upgrading backup: %w", err)
+	}
+	if err := store.Close(); err != nil {
+		os.Remove(staging)
+		return "", fmt.Errorf("upgrading backup: %w", err)
+	}
+	return staging, nil
+}
+
+// verifyBackup runs PRAGMA integrity_check on the database file at path.
+func verifyBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	defer db.Close()
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("checking backup: %w", err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("checking backup: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("checking backup: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrBackupCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// backupFileName names a snapshot taken at t so names sort chronologically.
+func backupFileName(t time.Time) string {
+	return "quotes-" + t.UTC().Format(backupTimeLayout) + ".db"
+}
+
+// parseBackupFileName returns when a snapshot was taken, or false for files that are not snapshots.
+func parseBackupFileName(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, "quotes-")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ".db")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeLayout, stamp)
+	return t, err == nil
+}
+
+// listBackups returns the snapshots in dir, newest first. A missing directory has no snapshots.
+func listBackups(dir string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading backup directory: %w", err)
+	}
+	var backups []BackupInfo
+	for _, entry := range entries {
+		created, ok := parseBackupFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Path: filepath.Join(dir, entry.Name()), CreatedAt: created, Size: info.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// rotateBackups removes all but the newest keep snapshots in dir and returns the removed ones.
+func rotateBackups(dir string, keep int) ([]BackupInfo, error) {
+	backups, err := listBackups(dir)
+	if err != nil || keep <= 0 || len(backups) <= keep {
+		return nil, err
+	}
+	var removed []BackupInfo
+	for _, b := range backups[keep:] {
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("removing old backup: %w", err)
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
+
+// resolveBackupPath accepts either a path to a snapshot or the name of one in dir.
+func resolveBackupPath(dir, name string) string {
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return name
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		return filepath.Join(dir, name)
+	}
+	return name
+}
+
+// backupAndRotate takes a snapshot and prunes old ones according to settings.
+func backupAndRotate(ctx context.Context, store *QuoteStore, settings BackupSettings) (BackupInfo, error) {
+	info, err := store.Backup(ctx, settings.Dir)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	if _, err := rotateBackups(settings.Dir, settings.Keep); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// runBackupScheduler takes a snapshot every settings.Interval until ctx is canceled. The first
+// snapshot is taken one interval after start. It is a no-op when the schedule is disabled.
+func runBackupScheduler(ctx context.Context, store *QuoteStore, settings BackupSettings) {
+	if settings.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(settings.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := backupAndRotate(ctx, store, settings)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Error backing up database: %v", err)
+		case err == nil:
+			log.Printf("Backed up database to %s", info.Path)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BulkOptions controls AddMany, UpdateMany and DeleteMany.
+type BulkOptions struct {
+	// Actor is recorded in the history of the quotes a batch changes. AddMany only uses it for
+	// quotes whose own Actor is empty.
+	Actor string
+	// Progress, when set, is called after each row with how many rows are done out of total. It
+	// runs while the batch's transaction is open, so it should return quickly, and counts up from
+	// the start again if the batch is retried after another process held the database lock.
+	Progress func(done, total int)
+}
+
+func (o BulkOptions) progress(done, total int) {
+	if o.Progress != nil {
+		o.Progress(done, total)
+	}
+}
+
+// QuoteUpdate is one change made by UpdateMany to the live quote numbered ID. An empty Text or
+// Author leaves that field unchanged.
+type QuoteUpdate struct {
+	ID     int
+	Text   string
+	Author string
+}
+
+// RowError is the reason one row of a batch was rejected. Row is the row's 1-based position in
+// the batch.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError reports the rows that stopped a batch. A batch that fails this way has written
+// nothing. Rows lists every row that failed validation, or the single row whose write failed.
+type BulkError struct {
+	Rows []RowError
+}
+
+func (e *BulkError) Error() string {
+	if len(e.Rows) == 1 {
+		return fmt.Sprintf("batch rolled back: %v", e.Rows[0])
+	}
+	return fmt.Sprintf("batch rolled back: %d rows rejected, first %v", len(e.Rows), e.Rows[0])
+}
+
+// Unwrap exposes the row errors so errors.As finds, e.g., a *DuplicateQuoteError.
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Rows))
+	for i, row := range e.Rows {
+		errs[i] = row.Err
+	}
+	return errs
+}
+
+// rowFailed wraps the error that stopped the batch at index i.
+func rowFailed(i int, err error) error {
+	return &BulkError{Rows: []RowError{{Row: i + 1, Err: err}}}
+}
+
+// validateAddMany checks every quote of an AddMany batch, including against the batch's other
+// quotes, and returns them with opts.Actor filled in where they have none.
+func validateAddMany(quotes []NewQuote, opts BulkOptions) ([]NewQuote, error) {
+	var bulkErr BulkError
+	prepared := make([]NewQuote, len(quotes))
+	seen := make(map[string]int)
+	for i, nq := range quotes {
+		if strings.TrimSpace(nq.Actor) == "" {
+			nq.Actor = opts.Actor
+		}
+		prepared[i] = nq
+		text, _, _, err := validateNewQuote(nq)
+		if err != nil {
+			bulkErr.Rows = append(bulkErr.Rows, RowError{Row: i + 1, Err: err})
+			continue
+		}
+		fingerprint := quoteFingerprint(text)
+		if first, ok := seen[fingerprint]; ok && fingerprint != "" {
+			bulkErr.Rows = append(bulkErr.Rows, RowError{Row: i + 1, Err: fmt.Errorf("same quote as row %d", first)})
+			continue
+		}
+		seen[fingerprint] = i + 1
+	}
+	if len(bulkErr.Rows) > 0 {
+		return nil, &bulkErr
+	}
+	return prepared, nil
+}
+
+// validateUpdateMany checks every change of an UpdateMany batch and returns them trimmed.
+func validateUpdateMany(updates []QuoteUpdate) ([]QuoteUpdate, error) {
+	var bulkErr BulkError
+	trimmed := make([]QuoteUpdate, len(updates))
+	for i, u := range updates {
+		u.Text = strings.TrimSpace(u.Text)
+		u.Author = strings.TrimSpace(u.Author)
+		trimmed[i] = u
+		if u.Text == "" && u.Author == "" {
+			bulkErr.Rows = append(bulkErr.Rows, RowError{Row: i + 1, Err: fmt.Errorf("nothing to change for quote #%d", u.ID)})
+		}
+	}
+	if len(bulkErr.Rows) > 0 {
+		return nil, &bulkErr
+	}
+	return trimmed, nil
+}
+
+// validateDeleteMany rejects quote numbers listed more than once in a DeleteMany batch.
+func validateDeleteMany(ids []int) error {
+	var bulkErr BulkError
+	seen := make(map[int]int)
+	for i, id := range ids {
+		if first, ok := seen[id]; ok {
+			bulkErr.Rows = append(bulkErr.Rows, RowError{Row: i + 1, Err: fmt.Errorf("quote #%d already listed in row %d", id, first)})
+			continue
+		}
+		seen[id] = i + 1
+	}
+	if len(bulkErr.Rows) > 0 {
+		return &bulkErr
+	}
+	return nil
+}
+
+// bulkStatements are the statements a batch runs for every row, prepared once per transaction.
+type bulkStatements []*sql.Stmt
+
+func prepareBulk(ctx context.Context, tx *sql.Tx, queries ...string) (bulkStatements, error) {
+	stmts := make(bulkStatements, 0, len(queries))
+	for _, query := range queries {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			stmts.Close()
+			return nil, fmt.Errorf("preparing statement: %w", err)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func (stmts bulkStatements) Close() {
+	for _, stmt := range stmts {
+		stmt.Close()
+	}
+}
+
+// liveQuoteStmt is liveQuoteTx using a prepared statement.
+func liveQuoteStmt(ctx context.Context, stmt *sql.Stmt, channel string, id int) (Quote, error) {
+	q, err := scanQuote(stmt.QueryRowContext(ctx, channel, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Quote{}, fmt.Errorf("no quote with id %d found", id)
+		}
+		return Quote{}, fmt.Errorf("fetching quote: %w", err)
+	}
+	return q, nil
+}
+
+// AddMany adds quotes to channel in a single transaction and returns their numbers in order. Each
+// quote is checked as Add checks it, except that near-duplicates are not reported, and keeps its
+// CreatedAt when set. If any quote is rejected, nothing is added and the error is a *BulkError.
+func (s *QuoteStore) AddMany(ctx context.Context, channel string, quotes []NewQuote, opts BulkOptions) ([]int, error) {
+	quotes, err := validateAddMany(quotes, opts)
+	if err != nil {
+		return nil, err
+	}
+	channel = normalizeChannel(channel)
+	ids := make([]int, len(quotes))
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		stmts, err := prepareBulk(ctx, tx, duplicateQuery, nextSeqQuery, insertQuoteQuery, insertRevisionQuery)
+		if err != nil {
+			return err
+		}
+		defer stmts.Close()
+		duplicate, nextSeq, insert, revision := stmts[0], stmts[1], stmts[2], stmts[3]
+
+		for i, nq := range quotes {
+			text, author, tags, _ := validateNewQuote(nq)
+			fingerprint := quoteFingerprint(text)
+			if fingerprint != "" {
+				var existing int
+				switch err := duplicate.QueryRowContext(ctx, channel, fingerprint).Scan(&existing); {
+				case err == nil:
+					return rowFailed(i, &DuplicateQuoteError{ExistingID: existing})
+				case !errors.Is(err, sql.ErrNoRows):
+					return rowFailed(i, fmt.Errorf("checking for duplicates: %w", err))
+				}
+			}
+			if err := nextSeq.QueryRowContext(ctx, channel).Scan(&ids[i]); err != nil {
+				return rowFailed(i, fmt.Errorf("allocating quote number: %w", err))
+			}
+			res, err := insert.ExecContext(ctx, insertQuoteArgs(channel, ids[i], text, author, fingerprint, nq)...)
+			if err != nil {
+				return rowFailed(i, fmt.Errorf("executing insert: %w", err))
+			}
+			rowID, err := res.LastInsertId()
+			if err != nil {
+				return rowFailed(i, err)
+			}
+			if _, err := addTagsTx(ctx, tx, rowID, tags); err != nil {
+				return rowFailed(i, err)
+			}
+			rev := Revision{QuoteID: ids[i], Channel: channel, quoteRowID: rowID, Actor: nq.Actor, Action: RevisionAdd, NewText: text, NewAuthor: author, Note: formatTags(tags)}
+			if _, err := revision.ExecContext(ctx, revisionArgs(rev, time.Now())...); err != nil {
+				return rowFailed(i, fmt.Errorf("recording revision: %w", err))
+			}
+			opts.progress(i+1, len(quotes))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UpdateMany applies updates to live quotes in channel in a single transaction, recording an edit
+// and/or author revision for each as UpdateText and UpdateAuthor do. If any update is rejected,
+// nothing is changed and the error is a *BulkError.
+func (s *QuoteStore) UpdateMany(ctx context.Context, channel string, updates []QuoteUpdate, opts BulkOptions) error {
+	updates, err := validateUpdateMany(updates)
+	if err != nil {
+		return err
+	}
+	channel = normalizeChannel(channel)
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmts, err := prepareBulk(ctx, tx, liveQuoteQuery, "UPDATE quotes SET text = ?, author = ?, fingerprint = ? WHERE id = ?", insertRevisionQuery)
+		if err != nil {
+			return err
+		}
+		defer stmts.Close()
+		load, update, revision := stmts[0], stmts[1], stmts[2]
+
+		for i, u := range updates {
+			old, err := liveQuoteStmt(ctx, load, channel, u.ID)
+			if err != nil {
+				return rowFailed(i, err)
+			}
+			revs := updateRevisions(old, u, opts.Actor)
+			updated := revs[len(revs)-1]
+			if _, err := update.ExecContext(ctx, updated.NewText, updated.NewAuthor, quoteFingerprint(updated.NewText), old.RowID); err != nil {
+				return rowFailed(i, fmt.Errorf("updating quote: %w", err))
+			}
+			for _, rev := range revs {
+				if _, err := revision.ExecContext(ctx, revisionArgs(rev, time.Now())...); err != nil {
+					return rowFailed(i, fmt.Errorf("recording revision: %w", err))
+				}
+			}
+			opts.progress(i+1, len(updates))
+		}
+		return nil
+	})
+}
+
+// updateRevisions returns the revisions u records against old: an edit when it sets the text,
+// then an author change when it sets the author. The last revision holds the quote's new state.
+func updateRevisions(old Quote, u QuoteUpdate, actor string) []Revision {
+	var revs []Revision
+	if u.Text != "" {
+		rev := snapshotRevision(old, actor, RevisionEdit)
+		rev.NewText = u.Text
+		revs = append(revs, rev)
+		old.Text = u.Text
+	}
+	if u.Author != "" {
+		rev := snapshotRevision(old, actor, RevisionAuthor)
+		rev.NewAuthor = u.Author
+		revs = append(revs, rev)
+	}
+	return revs
+}
+
+// DeleteMany moves the live quotes numbered ids in channel to the trash in a single transaction,
+// as Delete does. If any quote cannot be deleted, nothing is and the error is a *BulkError.
+func (s *QuoteStore) DeleteMany(ctx context.Context, channel string, ids []int, opts BulkOptions) error {
+	if err := validateDeleteMany(ids); err != nil {
+		return err
+	}
+	channel = normalizeChannel(channel)
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmts, err := prepareBulk(ctx, tx, liveQuoteQuery, "UPDATE quotes SET deleted_at = ?, deleted_by = ? WHERE id = ?", insertRevisionQuery)
+		if err != nil {
+			return err
+		}
+		defer stmts.Close()
+		load, trash, revision := stmts[0], stmts[1], stmts[2]
+
+		for i, id := range ids {
+			old, err := liveQuoteStmt(ctx, load, channel, id)
+			if err != nil {
+				return rowFailed(i, err)
+			}
+			now := time.Now()
+			if _, err := trash.ExecContext(ctx, formatSQLiteTime(now), opts.Actor, old.RowID); err != nil {
+				return rowFailed(i, fmt.Errorf("deleting quote: %w", err))
+			}
+			if _, err := revision.ExecContext(ctx, revisionArgs(snapshotRevision(old, opts.Actor, RevisionDelete), now)...); err != nil {
+				return rowFailed(i, fmt.Errorf("recording revision: %w", err))
+			}
+			opts.progress(i+1, len(ids))
+		}
+		return nil
+	})
+}
@@ -8,48 +8,77 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // runCLI starts an interactive command-line loop that accepts user commands to manage quotes
-// using the provided QuoteStore and delegates unrecognized commands to the provided CommandHandler.
-// It prompts on stdin for commands (add, random, search, get, latest, count, list, delete, help, exit),
-// performs the corresponding store operations, prints results to stdout, and returns when the user
-// issues "exit" or when an input error occurs.
-func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
+// using the provided QuoteRepository and delegates unrecognized commands to the provided CommandHandler.
+// It prompts on stdin for commands (add, random, search, get, latest, count, list, delete, restore, trash, purge, tag, untag, tags, history, revert, stats, dedupe, backup, migrate, template, channel, help, exit),
+// performs the corresponding store operations on the current channel (initially the first configured
+// one), prints results to stdout, and returns when the user issues "exit" or when an input error occurs.
+func runCLI(ctx context.Context, store QuoteRepository, handler *CommandHandler, config AppConfig) {
 	reader := bufio.NewReader(os.Stdin)
+	channel := config.PrimaryChannel()
+	randomSettings := handler.randomSettings
+	display := handler.channels.Display()
+	channelSettings := handler.channels
 	for {
-		fmt.Println("Enter command (add, random, search, get, latest, count, list, delete, help, exit):")
+		fmt.Println("Enter command (add, random, search, get, latest, count, list, delete, restore, trash, purge, tag, untag, tags, history, revert, stats, dedupe, backup, migrate, template, channel, help, exit):")
 		input, err := reader.ReadString('\n')
 		if err != nil {
 			fmt.Println("Error reading input:", err)
 			return
 		}
 		input = strings.TrimSpace(input)
+		fields := strings.Fields(input)
+		var command string
+		var args []string
+		if len(fields) > 0 {
+			command = strings.ToLower(fields[0])
+			args = fields[1:]
+		}
 
-		switch strings.ToLower(input) {
+		switch command {
 		case "help":
-			fmt.Println(printHelp())
+			fmt.Println(handler.Help(channel, strings.Join(args, " ")))
 		case "add":
-			fmt.Println("Enter quote text:")
+			fmt.Println("Enter quote text (trailing #hashtags become tags):")
 			quoteText, _ := reader.ReadString('\n')
-			quoteText = strings.TrimSpace(quoteText)
+			quoteText, tags := splitHashtags(quoteText)
 			fmt.Println("Enter author (leave blank to use default):")
 			author, _ := reader.ReadString('\n')
 			author = strings.TrimSpace(author)
 			if author == "" {
 				author = "CLI"
 			}
-			id, err := store.Add(ctx, quoteText, author)
+			fmt.Println("Enter game/category (leave blank to skip):")
+			game, _ := reader.ReadString('\n')
+			added, err := store.Add(ctx, channel, NewQuote{
+				Text:     quoteText,
+				Author:   author,
+				Actor:    "CLI",
+				Platform: PlatformCLI,
+				Tags:     tags,
+				Game:     strings.TrimSpace(game),
+			})
 			if err != nil {
 				fmt.Println("Error adding quote:", err)
 				continue
 			}
-			fmt.Printf("Quote added with ID #%d.\n", id)
+			fmt.Printf("Quote added with ID #%d.\n", added.ID)
+			if added.Similar != nil {
+				fmt.Printf("Warning: it is %.0f%% similar to %s\n", added.Similar.Score*100, formatQuote(added.Similar.Quote))
+			}
 		case "random":
-			q, err := store.Random(ctx)
+			opts, ok := parseRandomOptions(args, randomSettings)
+			if !ok {
+				fmt.Println("Usage: random [fresh] [#tag] [game:<name>]")
+				continue
+			}
+			q, err := store.Random(ctx, channel, opts)
 			if err != nil {
 				if errors.Is(err, ErrNoQuotes) {
-					fmt.Println("No quotes have been added yet.")
+					fmt.Println("No matching quotes found.")
 				} else {
 					fmt.Println("Error fetching quote:", err)
 				}
@@ -57,10 +86,13 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 			}
 			fmt.Println(formatQuote(*q))
 		case "search":
-			fmt.Println("Enter search term:")
-			term, _ := reader.ReadString('\n')
-			term = strings.TrimSpace(term)
-			results, err := store.Search(ctx, term)
+			term := strings.Join(args, " ")
+			if term == "" {
+				fmt.Println("Enter search query:")
+				term, _ = reader.ReadString('\n')
+				term = strings.TrimSpace(term)
+			}
+			results, total, err := store.Search(ctx, channel, term, 20)
 			if err != nil {
 				if errors.Is(err, ErrNoQuotes) {
 					fmt.Println("No matching quotes found.")
@@ -69,8 +101,13 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 				}
 				continue
 			}
-			for _, q := range results {
-				fmt.Println(formatQuote(q))
+			fmt.Printf("%d match%s, best first:\n", total, pluralize("", "es", total))
+			for _, res := range results {
+				fmt.Println(formatQuote(res.Quote))
+				fmt.Printf("    %s\n", res.Snippet)
+			}
+			if total > len(results) {
+				fmt.Printf("... %d more not shown. Refine the query to narrow it down.\n", total-len(results))
 			}
 		case "get":
 			fmt.Println("Enter quote ID:")
@@ -81,7 +118,7 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 				fmt.Println("Invalid ID")
 				continue
 			}
-			q, err := store.GetByID(ctx, id)
+			q, err := store.GetByID(ctx, channel, id)
 			if err != nil {
 				if errors.Is(err, ErrNoQuotes) {
 					fmt.Printf("No quote with ID #%d found.\n", id)
@@ -90,9 +127,16 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 				}
 			} else {
 				fmt.Println(formatQuote(*q))
+				fmt.Printf("    %s\n", display.For(channel).Added(q.CreatedAt, time.Now()))
+				if q.Game != "" || q.StreamTitle != "" {
+					fmt.Printf("    game: %s  title: %s\n", emptyPlaceholder(q.Game), emptyPlaceholder(q.StreamTitle))
+				}
+				if q.SubmittedBy != "" {
+					fmt.Printf("    submitted by: %s  id: %s  platform: %s  channel: #%s\n", q.SubmittedBy, emptyPlaceholder(q.SubmitterID), emptyPlaceholder(q.Platform), emptyPlaceholder(q.Channel))
+				}
 			}
 		case "latest":
-			q, err := store.Latest(ctx)
+			q, err := store.Latest(ctx, channel)
 			if err != nil {
 				if errors.Is(err, ErrNoQuotes) {
 					fmt.Println("No quotes have been added yet.")
@@ -100,28 +144,17 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 					fmt.Println("Error fetching latest quote:", err)
 				}
 			} else {
-				fmt.Printf("Latest is #%d: \"%s\" - %s\n", q.ID, q.Text, q.Author)
+				fmt.Printf("Latest is #%d: \"%s\" - %s (%s)\n", q.ID, q.Text, q.Author, display.For(channel).Added(q.CreatedAt, time.Now()))
 			}
 		case "count":
-			total, err := store.Count(ctx)
+			total, err := store.Count(ctx, channel)
 			if err != nil {
 				fmt.Println("Error counting quotes:", err)
 				continue
 			}
 			fmt.Printf("There %s %d quote%s saved.\n", pluralize("is", "are", total), total, pluralSuffix(total))
 		case "list":
-			quotes, err := store.List(ctx)
-			if err != nil {
-				if errors.Is(err, ErrNoQuotes) {
-					fmt.Println("No quotes found.")
-				} else {
-					fmt.Println("Error listing quotes:", err)
-				}
-			} else {
-				for _, q := range quotes {
-					fmt.Println(formatQuote(q))
-				}
-			}
+			runListCommand(ctx, store, channel, args)
 		case "delete":
 			fmt.Println("Enter quote ID to delete:")
 			idStr, _ := reader.ReadString('\n')
@@ -131,19 +164,472 @@ func runCLI(ctx context.Context, store *QuoteStore, handler *CommandHandler) {
 				fmt.Println("Invalid ID")
 				continue
 			}
-			if err := store.Delete(ctx, id); err != nil {
+			if err := store.Delete(ctx, channel, id, "CLI"); err != nil {
 				fmt.Printf("Error deleting quote #%d: %v\n", id, err)
 			} else {
-				fmt.Printf("Quote #%d deleted.\n", id)
+				fmt.Printf("Quote #%d moved to the trash.\n", id)
+			}
+		case "restore":
+			fmt.Println("Enter quote ID to restore:")
+			idStr, _ := reader.ReadString('\n')
+			idStr = strings.TrimSpace(idStr)
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				fmt.Println("Invalid ID")
+				continue
+			}
+			if err := store.Restore(ctx, channel, id, "CLI"); err != nil {
+				fmt.Printf("Error restoring quote #%d: %v\n", id, err)
+			} else {
+				fmt.Printf("Quote #%d restored.\n", id)
+			}
+		case "trash":
+			quotes, err := store.Trash(ctx, channel, 0)
+			if err != nil {
+				if errors.Is(err, ErrNoQuotes) {
+					fmt.Println("The trash is empty.")
+				} else {
+					fmt.Println("Error listing deleted quotes:", err)
+				}
+				continue
+			}
+			for _, q := range quotes {
+				fmt.Printf("%s (deleted by %s at %s)\n", formatQuote(q), emptyPlaceholder(q.DeletedBy), display.For(channel).Format(q.DeletedAt))
 			}
+		case "purge":
+			runPurgeCommand(ctx, store, config.TrashRetentionDays)
+		case "tag":
+			runTagCommand(ctx, store, channel, reader, args, true)
+		case "untag":
+			runTagCommand(ctx, store, channel, reader, args, false)
+		case "tags":
+			runTagsCommand(ctx, store, channel, args)
+		case "history":
+			runHistoryCommand(ctx, store, channel, display.For(channel), reader, args)
+		case "revert":
+			runRevertCommand(ctx, store, channel, reader, args)
+		case "backup":
+			runBackupCommand(ctx, store, config.BackupSettings(), reader, args)
+		case "stats":
+			runStatsCommand(ctx, store, channel, display.For(channel))
+		case "dedupe":
+			runDedupeCommand(ctx, store, channel, reader, args)
+		case "migrate":
+			runMigrateCommand(ctx, store, args)
+		case "template":
+			runTemplateCommand(channelSettings.For(channel), strings.TrimSpace(input[len(fields[0]):]))
+		case "channel":
+			if len(args) > 0 {
+				channel = normalizeChannel(args[0])
+			}
+			fmt.Printf("Working on quotes for #%s.\n", emptyPlaceholder(channel))
 		case "exit":
 			return
 		default:
 			// Fallback to the shared handler for misc commands (e.g. !quote)
-			responses := handler.Handle(ctx, input, "CLI", true)
+			responses := handler.Handle(ctx, channel, input, Sender{Platform: PlatformCLI, Name: "CLI", Role: RoleBroadcaster})
 			for _, resp := range responses {
 				fmt.Println(resp)
 			}
 		}
 	}
 }
+
+// promptArg returns args[idx] when it was given inline, otherwise it asks for the value on stdin.
+func promptArg(reader *bufio.Reader, args []string, idx int, prompt string) string {
+	if idx < len(args) {
+		return args[idx]
+	}
+	fmt.Println(prompt)
+	value, _ := reader.ReadString('\n')
+	return strings.TrimSpace(value)
+}
+
+// cliListPageSize is how many quotes the CLI list command prints per page.
+const cliListPageSize = 20
+
+// runListCommand prints one page of quotes; it accepts the same arguments as !quote list.
+func runListCommand(ctx context.Context, store QuoteRepository, channel string, args []string) {
+	req, err := parseListArgs(args)
+	if err != nil {
+		fmt.Println("Usage: list [page N] [sort id|date|author|rating] [desc] [by <author> | submitted-by <user>]:", err)
+		return
+	}
+	page, err := store.ListPage(ctx, channel, req.options(cliListPageSize))
+	if err != nil {
+		fmt.Println("Error listing quotes:", err)
+		return
+	}
+	if page.Total == 0 {
+		fmt.Println("No quotes found.")
+		return
+	}
+	pages := pageCount(page.Total, cliListPageSize)
+	if len(page.Quotes) == 0 {
+		fmt.Printf("There %s only %d page%s.\n", pluralize("is", "are", pages), pages, pluralSuffix(pages))
+		return
+	}
+	for _, q := range page.Quotes {
+		fmt.Println(formatQuote(q))
+	}
+	fmt.Printf("Page %d of %d (%d quote%s).", req.Page, pages, page.Total, pluralSuffix(page.Total))
+	if req.Page < pages {
+		fmt.Printf(" Use \"list page %d\" for more.", req.Page+1)
+	}
+	fmt.Println()
+}
+
+// cliStatsLimit is how many authors and quotes the CLI stats report ranks.
+const cliStatsLimit = 10
+
+// statsBarWidth is the length of the bar drawn for the busiest month in the stats report.
+const statsBarWidth = 40
+
+// runStatsCommand prints totals, the top authors, the most-shown quotes and a month-by-month
+// histogram of added quotes for channel.
+func runStatsCommand(ctx context.Context, store QuoteRepository, channel string, display TimeDisplay) {
+	stats, err := store.Stats(ctx, channel, cliStatsLimit)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			fmt.Println("No quotes have been added yet.")
+		} else {
+			fmt.Println("Error gathering stats:", err)
+		}
+		return
+	}
+	fmt.Printf("Quotes:  %d by %d author%s\n", stats.Total, stats.Authors, pluralSuffix(stats.Authors))
+	fmt.Printf("Views:   %d (%d quote%s never shown)\n", stats.Views, stats.NeverShown, pluralSuffix(stats.NeverShown))
+	if stats.LastShown != nil {
+		fmt.Printf("Last shown %s (%s): %s\n", display.Format(stats.LastShown.LastShownAt), relativeTime(stats.LastShown.LastShownAt, time.Now()), formatQuote(*stats.LastShown))
+	}
+
+	fmt.Println("\nTop authors:")
+	for _, ac := range stats.TopAuthors {
+		fmt.Printf("  %5d  %s\n", ac.Count, ac.Name)
+	}
+	if len(stats.MostShown) > 0 {
+		fmt.Println("\nMost shown:")
+		for _, q := range stats.MostShown {
+			fmt.Printf("  %5d  %s\n", q.Views, formatQuote(q))
+		}
+	}
+
+	months := monthsBetween(stats.AddedPerMonth)
+	busiest := 0
+	for _, mc := range months {
+		busiest = max(busiest, mc.Count)
+	}
+	fmt.Println("\nAdded per month (UTC):")
+	for _, mc := range months {
+		bar := strings.Repeat("#", (mc.Count*statsBarWidth+busiest-1)/busiest)
+		fmt.Printf("  %s  %5d  %s\n", mc.Month.Format(statsMonthLayout), mc.Count, bar)
+	}
+}
+
+// runTemplateCommand lists the replies response_templates can change with the templates the
+// channel uses for them, or previews one against a sample quote. With text after the reply's name
+// it previews that text instead, to try a template out before putting it in the config file.
+func runTemplateCommand(settings ChannelSettings, input string) {
+	name, text, _ := strings.Cut(input, " ")
+	name = strings.ToLower(name)
+	if name == "" {
+		for _, name := range responseNames() {
+			text, custom := settings.Templates.Text(name)
+			marker := " "
+			if custom {
+				marker = "*"
+			}
+			fmt.Printf("%s %-20s %s\n", marker, name, text)
+		}
+		fmt.Println("(* = set in response_templates; use template <name> [text] to preview one)")
+		return
+	}
+	preview, err := settings.PreviewResponse(name, strings.TrimSpace(text))
+	if err != nil {
+		fmt.Println("Invalid template:", err)
+		return
+	}
+	fmt.Println(preview)
+}
+
+// runBackupCommand handles "backup now", "backup list" and "backup restore <file>" for the SQLite store.
+func runBackupCommand(ctx context.Context, store QuoteRepository, settings BackupSettings, reader *bufio.Reader, args []string) {
+	sqlite, ok := store.(*QuoteStore)
+	if !ok {
+		fmt.Println("Backups only apply to the SQLite store.")
+		return
+	}
+	sub := "list"
+	if len(args) > 0 {
+		sub = strings.ToLower(args[0])
+	}
+	switch sub {
+	case "now":
+		info, err := backupAndRotate(ctx, sqlite, settings)
+		if err != nil {
+			fmt.Println("Error backing up database:", err)
+			return
+		}
+		fmt.Printf("Backed up to %s (%d bytes, integrity ok).\n", info.Path, info.Size)
+	case "list":
+		backups, err := listBackups(settings.Dir)
+		if err != nil {
+			fmt.Println("Error listing backups:", err)
+			return
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s yet.\n", settings.Dir)
+			return
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s  %d bytes\n", b.Name(), b.CreatedAt.Local().Format(time.RFC822), b.Size)
+		}
+		fmt.Printf("Keeping the newest %d in %s.\n", settings.Keep, settings.Dir)
+	case "restore":
+		name := strings.TrimSpace(strings.Join(args[1:], " "))
+		if name == "" {
+			name = promptArg(reader, nil, 0, "Enter backup file to restore:")
+		}
+		path := resolveBackupPath(settings.Dir, name)
+		if err := verifyBackup(ctx, path); err != nil {
+			fmt.Println("Cannot restore:", err)
+			return
+		}
+		answer := strings.ToLower(promptArg(reader, nil, 0, fmt.Sprintf("Replace all quotes with the contents of %s? A snapshot of the current database is taken first. [y/N]", path)))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Restore cancelled.")
+			return
+		}
+		safety, err := sqlite.Backup(ctx, settings.Dir)
+		if err != nil {
+			fmt.Println("Error taking a snapshot before restoring:", err)
+			return
+		}
+		fmt.Printf("Current database saved to %s.\n", safety.Path)
+		if err := sqlite.RestoreBackup(ctx, path); err != nil {
+			fmt.Println("Error restoring backup:", err)
+			return
+		}
+		fmt.Printf("Restored %s.\n", path)
+	default:
+		fmt.Println("Usage: backup [now|list|restore <file>]")
+	}
+}
+
+// runDedupeCommand scans the channel for duplicate and near-duplicate quotes and offers to merge
+// each pair found: the newer quote's tags are copied to the older one and the newer one is moved to
+// the trash. An optional argument sets the similarity threshold as a fraction or percentage.
+func runDedupeCommand(ctx context.Context, store QuoteRepository, channel string, reader *bufio.Reader, args []string) {
+	threshold := nearDuplicateThreshold
+	if len(args) > 0 {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "%"), 64)
+		if err != nil || value <= 0 || value > 100 {
+			fmt.Println("Usage: dedupe [threshold, e.g. 0.9 or 90%]")
+			return
+		}
+		if value > 1 {
+			value /= 100
+		}
+		threshold = value
+	}
+	quotes, err := store.List(ctx, channel)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			fmt.Println("No quotes found.")
+		} else {
+			fmt.Println("Error listing quotes:", err)
+		}
+		return
+	}
+	pairs := findDuplicates(quotes, threshold)
+	if len(pairs) == 0 {
+		fmt.Println("No duplicates found.")
+		return
+	}
+	fmt.Printf("Found %d possible duplicate%s.\n", len(pairs), pluralSuffix(len(pairs)))
+	merged := 0
+	for _, pair := range pairs {
+		fmt.Printf("\n%.0f%% similar:\n  keep  %s\n  drop  %s\n", pair.Score*100, formatQuote(pair.Keep), formatQuote(pair.Drop))
+		answer := strings.ToLower(promptArg(reader, nil, 0, fmt.Sprintf("Merge #%d into #%d? [y/N/q]", pair.Drop.ID, pair.Keep.ID)))
+		if answer == "q" {
+			break
+		}
+		if answer != "y" && answer != "yes" {
+			continue
+		}
+		if tags, err := store.TagsFor(ctx, channel, pair.Drop.ID); err == nil && len(tags) > 0 {
+			if _, err := store.AddTags(ctx, channel, pair.Keep.ID, tags, "CLI"); err != nil {
+				fmt.Printf("Error copying tags to quote #%d: %v\n", pair.Keep.ID, err)
+				continue
+			}
+		}
+		if err := store.Delete(ctx, channel, pair.Drop.ID, "CLI"); err != nil {
+			fmt.Printf("Error deleting quote #%d: %v\n", pair.Drop.ID, err)
+			continue
+		}
+		merged++
+		fmt.Printf("Merged #%d into #%d; #%d is in the trash.\n", pair.Drop.ID, pair.Keep.ID, pair.Drop.ID)
+	}
+	fmt.Printf("Merged %d pair%s.\n", merged, pluralSuffix(merged))
+}
+
+// runTagCommand adds (or, when add is false, removes) tags on a quote.
+func runTagCommand(ctx context.Context, store QuoteRepository, channel string, reader *bufio.Reader, args []string, add bool) {
+	id, err := strconv.Atoi(promptArg(reader, args, 0, "Enter quote ID:"))
+	if err != nil {
+		fmt.Println("Invalid ID")
+		return
+	}
+	var tags []string
+	if len(args) > 1 {
+		tags = args[1:]
+	} else {
+		tags = strings.Fields(promptArg(reader, nil, 0, "Enter tags separated by spaces:"))
+	}
+
+	if add {
+		added, err := store.AddTags(ctx, channel, id, tags, "CLI")
+		if err != nil {
+			fmt.Printf("Error tagging quote #%d: %v\n", id, err)
+			return
+		}
+		fmt.Printf("Quote #%d tagged %s.\n", id, emptyPlaceholder(formatTags(added)))
+		return
+	}
+	removed, err := store.RemoveTags(ctx, channel, id, tags, "CLI")
+	if err != nil {
+		fmt.Printf("Error untagging quote #%d: %v\n", id, err)
+		return
+	}
+	fmt.Printf("Removed %s from quote #%d.\n", emptyPlaceholder(formatTags(removed)), id)
+}
+
+// runTagsCommand lists every tag by usage, or the tags on a single quote when an ID is given.
+func runTagsCommand(ctx context.Context, store QuoteRepository, channel string, args []string) {
+	if len(args) > 0 {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("Invalid ID")
+			return
+		}
+		tags, err := store.TagsFor(ctx, channel, id)
+		if err != nil {
+			fmt.Printf("Error fetching tags for quote #%d: %v\n", id, err)
+			return
+		}
+		fmt.Printf("Quote #%d: %s\n", id, emptyPlaceholder(formatTags(tags)))
+		return
+	}
+	counts, err := store.TopTags(ctx, channel, 0)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			fmt.Println("No quotes have been tagged yet.")
+		} else {
+			fmt.Println("Error listing tags:", err)
+		}
+		return
+	}
+	for _, tc := range counts {
+		fmt.Printf("#%-20s %d\n", tc.Name, tc.Count)
+	}
+}
+
+// runHistoryCommand prints the full revision history of a quote as a diff, newest first.
+func runHistoryCommand(ctx context.Context, store QuoteRepository, channel string, display TimeDisplay, reader *bufio.Reader, args []string) {
+	id, err := strconv.Atoi(promptArg(reader, args, 0, "Enter quote ID:"))
+	if err != nil {
+		fmt.Println("Invalid ID")
+		return
+	}
+	revisions, err := store.History(ctx, channel, id, 0)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			fmt.Printf("No history recorded for quote #%d.\n", id)
+		} else {
+			fmt.Printf("Error fetching history for quote #%d: %v\n", id, err)
+		}
+		return
+	}
+	for _, rev := range revisions {
+		fmt.Println(formatRevisionDiff(rev, display))
+	}
+}
+
+// runRevertCommand rolls a quote back to the state recorded by one of its revisions.
+func runRevertCommand(ctx context.Context, store QuoteRepository, channel string, reader *bufio.Reader, args []string) {
+	id, err := strconv.Atoi(promptArg(reader, args, 0, "Enter quote ID:"))
+	if err != nil {
+		fmt.Println("Invalid ID")
+		return
+	}
+	revisionID, err := strconv.Atoi(strings.TrimPrefix(promptArg(reader, args, 1, "Enter revision number to roll back to:"), "r"))
+	if err != nil {
+		fmt.Println("Invalid revision")
+		return
+	}
+	if err := store.Revert(ctx, channel, id, revisionID, "CLI"); err != nil {
+		fmt.Printf("Error reverting quote #%d: %v\n", id, err)
+		return
+	}
+	fmt.Printf("Quote #%d rolled back to revision %d.\n", id, revisionID)
+}
+
+// runPurgeCommand permanently removes trashed quotes older than the configured retention.
+func runPurgeCommand(ctx context.Context, store QuoteRepository, retentionDays int) {
+	retention, ok := trashRetention(retentionDays)
+	if !ok {
+		fmt.Println("Purging is disabled (trash_retention_days is negative).")
+		return
+	}
+	purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+	if err != nil {
+		fmt.Println("Error purging trash:", err)
+		return
+	}
+	fmt.Printf("Purged %d quote%s deleted more than %d days ago.\n", purged, pluralSuffix(int(purged)), int(retention.Hours()/24))
+}
+
+// runMigrateCommand handles "migrate status" and "migrate up" from the CLI. Only the SQLite store
+// has a schema to migrate.
+func runMigrateCommand(ctx context.Context, repo QuoteRepository, args []string) {
+	store, ok := repo.(*QuoteStore)
+	if !ok {
+		fmt.Println("Migrations only apply to the SQLite store.")
+		return
+	}
+	action := "status"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+
+	switch action {
+	case "status":
+		statuses, err := store.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Println("Error reading migration status:", err)
+			return
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Local().Format(time.RFC822)
+			}
+			fmt.Printf("%4d  %-40s %s\n", st.Version, st.Name, state)
+		}
+	case "up":
+		applied, err := store.MigrateUp(ctx)
+		if err != nil {
+			fmt.Println("Error applying migrations:", err)
+			return
+		}
+		if len(applied) == 0 {
+			fmt.Printf("Schema is up to date (version %d).\n", latestSchemaVersion())
+			return
+		}
+		for _, version := range applied {
+			fmt.Printf("Applied migration %d.\n", version)
+		}
+	default:
+		fmt.Println("Usage: migrate status | migrate up")
+	}
+}
@@ -4,211 +4,946 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// CommandHandler turns incoming messages into responses using a QuoteStore.
+// CommandHandler turns incoming messages into responses using a QuoteRepository.
 type CommandHandler struct {
-	store *QuoteStore
+	store          QuoteRepository
+	streamInfo     *streamInfoOverride
+	randomSettings RandomSettings
+	channels       SettingsByChannel
+	commands       *CommandRegistry
+	follows        FollowerLookup
+	cooldowns      *cooldownTracker
 }
 
-// NewCommandHandler returns a new CommandHandler that uses the provided QuoteStore.
+// NewCommandHandler returns a new CommandHandler that uses the provided QuoteRepository.
 // Pass a non-nil store to enable quote operations; a nil store will leave the handler misconfigured.
-func NewCommandHandler(store *QuoteStore) *CommandHandler {
-	return &CommandHandler{store: store}
+// streamInfo supplies the game and title captured with new quotes and may be nil, in which case
+// only games set manually with !quote game are recorded. randomSettings controls weighting and the
+// window used by !quote random fresh; channels holds each channel's date display, approval policy,
+// triggers, permissions and cooldowns. When streamInfo is also a FollowerLookup it decides who counts as a
+// follower; otherwise subcommands limited to followers need a higher role.
+// The handler starts with the built-in subcommands plus those added with RegisterCommand.
+func NewCommandHandler(store QuoteRepository, streamInfo StreamInfoProvider, randomSettings RandomSettings, channels SettingsByChannel) *CommandHandler {
+	h := &CommandHandler{store: store, streamInfo: newStreamInfoOverride(streamInfo), randomSettings: randomSettings, channels: channels, cooldowns: newCooldownTracker()}
+	h.follows, _ = streamInfo.(FollowerLookup)
+	commands, err := h.buildRegistry(extraCommands)
+	if err != nil {
+		// RegisterCommand already rejected anything that clashes with the built-ins.
+		panic(err)
+	}
+	h.commands = commands
+	return h
 }
 
-func (h *CommandHandler) Handle(ctx context.Context, message, user string, isMod bool) []string {
+// Platforms a command or quote can come from.
+const (
+	PlatformTwitch = "twitch"
+	PlatformCLI    = "cli"
+)
+
+// Sender identifies who sent a command: the platform it arrived on, the user's stable ID there
+// (empty when the platform has none) and the display name used in replies and recorded as actor.
+// Login is the account name allow and deny lists match, falling back to Name, and Role is the
+// sender's standing in the channel as shown by their badges.
+type Sender struct {
+	Platform string
+	ID       string
+	Name     string
+	Login    string
+	Role     Role
+}
+
+// Handle processes a chat message sent by sender in channel and returns the replies to send.
+func (h *CommandHandler) Handle(ctx context.Context, channel, message string, sender Sender) []string {
 	if h == nil || h.store == nil {
 		return []string{"Quote handler is not configured"}
 	}
 
-	if !strings.HasPrefix(message, "!quote") {
+	name, args, ok := h.channels.For(channel).Triggers.match(strings.Fields(message))
+	if !ok {
 		return nil
 	}
+	if name == "" {
+		name = "random"
+	}
+	return h.dispatch(ctx, channel, name, args, sender)
+}
 
-	parts := strings.Fields(message)
-	if len(parts) == 0 {
-		return nil
+// builtinCommands returns the subcommands every handler starts with, in the order the help lists
+// them.
+func (h *CommandHandler) builtinCommands() []Command {
+	quoteID := ArgSpec{Name: "id", Kind: ArgQuoteID}
+	pendingID := ArgSpec{Name: "id", Kind: ArgPendingID}
+	return []Command{
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "random",
+			Args:    []ArgSpec{{Name: "filters", Kind: ArgText, Optional: true}},
+			Usage:   "[fresh] [#tag] [game:<name>]",
+			Summary: "Return a random quote, optionally filtered by tag or game; fresh prefers quotes not shown lately.",
+		}, Func: h.runRandom},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "add",
+			Args:    []ArgSpec{{Name: "quote", Kind: ArgText}},
+			Usage:   "[<author> |] <quote> [#tag ...]",
+			Summary: "Add a new quote; the author is the sender unless one is given before a |.",
+			Details: []string{`Trailing #hashtags on add (e.g. "... #rage #speedrun") become tags.`},
+		}, Func: h.runAdd},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "search",
+			Args:    []ArgSpec{{Name: "query", Kind: ArgText}},
+			Summary: "Show the best match and how many quotes matched.",
+			Details: []string{`Queries support "exact phrases", prefix*, author:name, OR, NOT/-word and (groups).`},
+		}, Func: h.runSearch},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "get",
+			Args:    []ArgSpec{quoteID},
+			Summary: "Get a specific quote by ID, with the game and stream title it was captured during, who submitted it and when.",
+		}, Func: h.runGet},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "by",
+			Args:    []ArgSpec{{Name: "author", Kind: ArgText}},
+			Summary: "Return a random quote said by an author.",
+		}, Func: h.runBy},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "submitted-by",
+			Aliases: []string{"submittedby"},
+			Args:    []ArgSpec{{Name: "user", Kind: ArgText}},
+			Summary: "Return a random quote added by a user.",
+		}, Func: h.runSubmittedBy},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "game",
+			Aliases: []string{"category"},
+			Args:    []ArgSpec{{Name: "game", Kind: ArgText, Optional: true}},
+			Usage:   "[name|auto]",
+			Summary: "Show the game new quotes are recorded with; with a name it runs setgame.",
+		}, Func: h.runGame},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "setgame",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{{Name: "game", Kind: ArgText}},
+			Usage:   "<name|auto>",
+			Summary: "Override the game new quotes are recorded with, or go back to Twitch's category with auto.",
+		}, Func: h.runSetGame},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "list",
+			Args:    []ArgSpec{{Name: "filters", Kind: ArgText, Optional: true}},
+			Usage:   "[page N] [sort id|date|author|rating] [desc] [by <author> | submitted-by <user>]",
+			Summary: "List quotes a page at a time, optionally only one author's or submitter's.",
+			Details: []string{`Add "sort date", "sort author" or "sort rating" and "desc" to change the order.`},
+		}, Func: h.runList},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "latest",
+			Summary: "Show the most recently added quote.",
+		}, Func: h.runLatest},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "count",
+			Summary: "Show how many quotes are stored.",
+		}, Func: h.runCount},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "stats",
+			Summary: "Show totals, top authors and the most-shown quotes.",
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.stats(ctx, call.Channel) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "delete",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID},
+			Summary: "Move a quote to the trash.",
+		}, Func: h.runDelete},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "restore",
+			Aliases: []string{"undelete"},
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID},
+			Summary: "Restore a deleted quote.",
+		}, Func: h.runRestore},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "trash",
+			Role:    RoleModerator,
+			Summary: "List recently deleted quotes.",
+		}, Func: h.runTrash},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "edit",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID, {Name: "quote", Kind: ArgText}},
+			Usage:   "<id> | <quote>",
+			Summary: "Update quote text.",
+		}, Func: h.runEdit},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "author",
+			Aliases: []string{"setauthor", "reauthor"},
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID, {Name: "author", Kind: ArgText}},
+			Summary: "Change quote author.",
+		}, Func: h.runAuthor},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "tag",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID, {Name: "tag...", Kind: ArgText}},
+			Summary: "Add tags to a quote.",
+		}, Func: h.runTag},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "untag",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID, {Name: "tag...", Kind: ArgText}},
+			Summary: "Remove tags from a quote.",
+		}, Func: h.runUntag},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "tags",
+			Args:    []ArgSpec{{Name: "id", Kind: ArgQuoteID, Optional: true}},
+			Summary: "List the most-used tags, or the tags on one quote.",
+		}, Func: h.runTags},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "history",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{quoteID},
+			Summary: "Show the last few changes to a quote.",
+		}, Func: h.runHistory},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "+1",
+			Aliases: []string{"upvote"},
+			Args:    []ArgSpec{quoteID},
+			Summary: "Vote a quote up (one vote per viewer per quote).",
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.runVote(ctx, call, 1) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "-1",
+			Aliases: []string{"downvote"},
+			Args:    []ArgSpec{quoteID},
+			Summary: "Vote a quote down.",
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.runVote(ctx, call, -1) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "top",
+			Args:    []ArgSpec{{Name: "N", Kind: ArgCount, Optional: true}},
+			Summary: "Show the highest-rated quotes.",
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.runLeaderboard(ctx, call, false) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "worst",
+			Args:    []ArgSpec{{Name: "N", Kind: ArgCount, Optional: true}},
+			Summary: "Show the lowest-rated quotes.",
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.runLeaderboard(ctx, call, true) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "pending",
+			Aliases: []string{"queue"},
+			Role:    RoleModerator,
+			Summary: "List quotes waiting for approval.",
+			Details: []string{"Channels with approval set to non-mods, non-vips or non-subs queue quotes added by senders below that role until a moderator approves them."},
+		}, Func: func(ctx context.Context, call *CommandCall) []string { return h.pending(ctx, call.Channel) }},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "approve",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{pendingID},
+			Summary: "Approve a pending quote.",
+		}, Func: h.runApprove},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "reject",
+			Role:    RoleModerator,
+			Args:    []ArgSpec{pendingID, {Name: "reason", Kind: ArgText, Optional: true}},
+			Summary: "Reject a pending quote.",
+		}, Func: h.runReject},
+		CommandFunc{CommandInfo: CommandInfo{
+			Name:    "help",
+			Args:    []ArgSpec{{Name: "command", Kind: ArgText, Optional: true}},
+			Summary: "Show this help message, or the details of one command.",
+		}, Func: func(_ context.Context, call *CommandCall) []string {
+			return []string{h.helpFor(call.Channel, call.Text("command"), call.Sender)}
+		}},
 	}
+}
 
-	if len(parts) == 1 {
-		quote, err := h.store.Random(ctx)
-		if err != nil {
-			if errors.Is(err, ErrNoQuotes) {
-				return []string{"No quotes have been added yet. Try !quote add to add one!"}
-			}
-			return []string{fmt.Sprintf("Error fetching quote: %v", err)}
-		}
-		return []string{formatQuote(*quote)}
+func (h *CommandHandler) runRandom(ctx context.Context, call *CommandCall) []string {
+	opts, ok := parseRandomOptions(call.Args, h.randomSettings)
+	if !ok {
+		return call.Reply("usage", replyData{"usage": call.Usage()})
 	}
+	return h.random(ctx, call.Channel, opts)
+}
 
-	subcmd := strings.ToLower(parts[1])
-	switch subcmd {
-	case "help":
-		return []string{printHelp()}
-	case "add":
-		if len(parts) < 3 {
-			return []string{"Usage: !quote add <quote text>"}
+func (h *CommandHandler) runAdd(ctx context.Context, call *CommandCall) []string {
+	channel, user := call.Channel, call.Sender.Name
+	quoteText, tags := splitHashtags(call.Text("quote"))
+	author := user
+	if pieces := strings.SplitN(quoteText, "|", 2); len(pieces) == 2 {
+		if customAuthor := strings.TrimSpace(pieces[0]); customAuthor != "" {
+			author = customAuthor
 		}
-		quoteText := strings.Join(parts[2:], " ")
-		author := user
-		if pieces := strings.SplitN(quoteText, "|", 2); len(pieces) == 2 {
-			if customAuthor := strings.TrimSpace(pieces[0]); customAuthor != "" {
-				author = customAuthor
-			}
-			quoteText = strings.TrimSpace(pieces[1])
-		}
-		id, err := h.store.Add(ctx, strings.TrimSpace(quoteText), author)
-		if err != nil {
-			return []string{fmt.Sprintf("Error adding quote: %v", err)}
-		}
-		return []string{fmt.Sprintf("Quote added with ID #%d.", id)}
-	case "search":
-		if len(parts) < 3 {
-			return []string{"Usage: !quote search <term>"}
-		}
-		term := strings.Join(parts[2:], " ")
-		results, err := h.store.Search(ctx, term)
+		quoteText = strings.TrimSpace(pieces[1])
+	}
+	info := h.lookupStreamInfo(ctx, channel)
+	nq := NewQuote{
+		Text:        quoteText,
+		Author:      author,
+		Actor:       user,
+		ActorID:     call.Sender.ID,
+		Platform:    call.Sender.Platform,
+		Tags:        tags,
+		Game:        info.Game,
+		StreamTitle: info.Title,
+	}
+	if h.channels.For(channel).Approval.Requires(call.Sender.Role) {
+		pending, err := h.store.Submit(ctx, channel, nq)
 		if err != nil {
-			if errors.Is(err, ErrNoQuotes) {
-				return []string{"No matching quotes found."}
+			if reply, ok := h.duplicateReply(channel, err); ok {
+				return []string{reply}
 			}
-			return []string{fmt.Sprintf("Error searching quotes: %v", err)}
+			return h.replyError(channel, "submitting quote", err)
 		}
-		return []string{formatQuote(results[0])}
-	case "get":
-		if len(parts) < 3 {
-			return []string{"Usage: !quote get <id>"}
+		return call.Reply("submitted", replyData{"pending_id": pending.ID})
+	}
+	added, err := h.store.Add(ctx, channel, nq)
+	if err != nil {
+		if reply, ok := h.duplicateReply(channel, err); ok {
+			return []string{reply}
 		}
-		id, err := strconv.Atoi(parts[2])
-		if err != nil {
-			return []string{"Invalid quote ID."}
+		return h.replyError(channel, "adding quote", err)
+	}
+	return call.Reply("added", replyData{"id": added.ID, "tags": formatTags(tags), "similar_id": similarID(added)})
+}
+
+func (h *CommandHandler) runSearch(ctx context.Context, call *CommandCall) []string {
+	results, total, err := h.store.Search(ctx, call.Channel, call.Text("query"), 1)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("search_none", replyData{"query": call.Text("query")})
 		}
-		quote, err := h.store.GetByID(ctx, id)
-		if err != nil {
-			if errors.Is(err, ErrNoQuotes) {
-				return []string{fmt.Sprintf("No quote with ID #%d found.", id)}
-			}
-			return []string{fmt.Sprintf("Error fetching quote #%d: %v", id, err)}
+		if errors.Is(err, ErrInvalidSearch) {
+			return call.Reply("search_invalid", replyData{"query": call.Text("query"), "error": err.Error()})
 		}
-		return []string{formatQuote(*quote)}
-	case "list":
-		quotes, err := h.store.List(ctx)
-		if err != nil {
-			if errors.Is(err, ErrNoQuotes) {
-				return []string{"No quotes found."}
-			}
-			return []string{fmt.Sprintf("Error listing quotes: %v", err)}
+		return h.replyError(call.Channel, "searching quotes", err)
+	}
+	h.recordView(ctx, call.Channel, results[0].Quote)
+	return call.ReplyQuote("search", &results[0].Quote, replyData{"total": total, "query": call.Text("query")})
+}
+
+func (h *CommandHandler) runGet(ctx context.Context, call *CommandCall) []string {
+	channel, id := call.Channel, call.Int("id")
+	quote, err := h.store.GetByID(ctx, channel, id)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("get_none", replyData{"id": id})
 		}
-		var respParts []string
-		for i, q := range quotes {
-			if i >= 5 {
-				break
-			}
-			respParts = append(respParts, formatQuote(q))
+		return h.replyError(channel, fmt.Sprintf("fetching quote #%d", id), err)
+	}
+	h.recordView(ctx, channel, *quote)
+	tags, _ := h.store.TagsFor(ctx, channel, id)
+	return call.ReplyQuote("get", quote, replyData{
+		"context":   formatStreamContext(*quote),
+		"submitted": formatSubmitter(*quote),
+		"added":     h.channels.For(channel).Display.Added(quote.CreatedAt, time.Now()),
+		"tags":      formatTags(tags),
+	})
+}
+
+func (h *CommandHandler) runBy(ctx context.Context, call *CommandCall) []string {
+	return h.random(ctx, call.Channel, RandomOptions{Weight: h.randomSettings.Weight, Author: userArg(call.Args)})
+}
+
+func (h *CommandHandler) runSubmittedBy(ctx context.Context, call *CommandCall) []string {
+	return h.random(ctx, call.Channel, RandomOptions{Weight: h.randomSettings.Weight, SubmittedBy: userArg(call.Args)})
+}
+
+func (h *CommandHandler) runVote(ctx context.Context, call *CommandCall, value int) []string {
+	id := call.Int("id")
+	result, err := h.store.Vote(ctx, call.Channel, id, voterKey(call.Sender), value)
+	if err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("voting on quote #%d", id), err)
+	}
+	data := replyData{"id": id, "vote": formatScore(value), "score": formatScore(result.Score)}
+	if !result.Changed {
+		return call.Reply("vote_unchanged", data)
+	}
+	return call.Reply("vote", data)
+}
+
+func (h *CommandHandler) runLeaderboard(ctx context.Context, call *CommandCall, worst bool) []string {
+	limit := 3
+	if call.Has("N") {
+		limit = min(call.Int("N"), chatListPageSize)
+	}
+	return h.leaderboard(ctx, call.Channel, limit, worst)
+}
+
+func (h *CommandHandler) runGame(ctx context.Context, call *CommandCall) []string {
+	channel := call.Channel
+	if !call.Has("game") {
+		if game, ok := h.streamInfo.Override(channel); ok {
+			return call.Reply("game_manual", replyData{"game": game})
 		}
-		return []string{strings.Join(respParts, " | ")}
-	case "latest":
-		quote, err := h.store.Latest(ctx)
-		if err != nil {
-			if errors.Is(err, ErrNoQuotes) {
-				return []string{"No quotes have been added yet."}
-			}
-			return []string{fmt.Sprintf("Error fetching latest quote: %v", err)}
+		info := h.lookupStreamInfo(ctx, channel)
+		if info.Game == "" {
+			return call.Reply("game_unknown", nil)
 		}
-		response := fmt.Sprintf("Latest is #%d: \"%s\" - %s (added %s)", quote.ID, quote.Text, quote.Author, quote.CreatedAt.Format(time.RFC822))
-		return []string{response}
-	case "count":
-		total, err := h.store.Count(ctx)
-		if err != nil {
-			return []string{fmt.Sprintf("Error counting quotes: %v", err)}
-		}
-		if total == 0 {
-			return []string{"No quotes have been added yet."}
+		return call.Reply("game", replyData{"game": info.Game})
+	}
+	// Setting the game goes through setgame, so its roles, lists and cooldowns apply.
+	return h.dispatch(ctx, channel, "setgame", call.Args, call.Sender)
+}
+
+func (h *CommandHandler) runSetGame(_ context.Context, call *CommandCall) []string {
+	channel := call.Channel
+	game := strings.TrimSpace(call.Text("game"))
+	if strings.EqualFold(game, "auto") || strings.EqualFold(game, "clear") {
+		h.streamInfo.SetGame(channel, "")
+		return call.Reply("game_cleared", nil)
+	}
+	h.streamInfo.SetGame(channel, game)
+	return call.Reply("game", replyData{"game": game})
+}
+
+func (h *CommandHandler) runList(ctx context.Context, call *CommandCall) []string {
+	req, err := parseListArgs(call.Args)
+	if err != nil {
+		return call.Reply("usage", replyData{"usage": call.Usage()})
+	}
+	return h.list(ctx, call.Channel, req)
+}
+
+func (h *CommandHandler) runLatest(ctx context.Context, call *CommandCall) []string {
+	quote, err := h.store.Latest(ctx, call.Channel)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("empty", nil)
 		}
-		return []string{fmt.Sprintf("There %s %d quote%s saved.", pluralize("is", "are", total), total, pluralSuffix(total))}
-	case "delete":
-		if len(parts) < 3 {
-			return []string{"Usage: !quote delete <id>"}
+		return h.replyError(call.Channel, "fetching latest quote", err)
+	}
+	h.recordView(ctx, call.Channel, *quote)
+	return call.ReplyQuote("latest", quote, nil)
+}
+
+func (h *CommandHandler) runCount(ctx context.Context, call *CommandCall) []string {
+	total, err := h.store.Count(ctx, call.Channel)
+	if err != nil {
+		return h.replyError(call.Channel, "counting quotes", err)
+	}
+	if total == 0 {
+		return call.Reply("empty", nil)
+	}
+	return call.Reply("count", replyData{"count": total})
+}
+
+func (h *CommandHandler) runDelete(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	if err := h.store.Delete(ctx, call.Channel, id, call.Sender.Name); err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("deleting quote #%d", id), err)
+	}
+	return call.Reply("deleted", replyData{"id": id})
+}
+
+func (h *CommandHandler) runRestore(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	if err := h.store.Restore(ctx, call.Channel, id, call.Sender.Name); err != nil {
+		if reply, ok := h.duplicateReply(call.Channel, err); ok {
+			return call.Reply("restore_duplicate", replyData{"id": id, "reason": reply})
 		}
-		if !isMod {
-			return []string{"Only Twitch moderators can delete quotes."}
+		return h.replyError(call.Channel, fmt.Sprintf("restoring quote #%d", id), err)
+	}
+	return call.Reply("restored", replyData{"id": id})
+}
+
+func (h *CommandHandler) runTrash(ctx context.Context, call *CommandCall) []string {
+	quotes, err := h.store.Trash(ctx, call.Channel, 5)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("trash_empty", nil)
 		}
-		id, err := strconv.Atoi(parts[2])
+		return h.replyError(call.Channel, "listing deleted quotes", err)
+	}
+	settings := h.channels.For(call.Channel)
+	var respParts []string
+	for _, q := range quotes {
+		respParts = append(respParts, settings.formatTrashedQuote(q))
+	}
+	return call.Reply("trash", replyData{"quotes": strings.Join(respParts, " | ")})
+}
+
+func (h *CommandHandler) runEdit(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	newText := strings.TrimSpace(strings.TrimPrefix(call.Text("quote"), "|"))
+	if newText == "" {
+		return call.Reply("usage", replyData{"usage": call.Usage()})
+	}
+	if err := h.store.UpdateText(ctx, call.Channel, id, newText, call.Sender.Name); err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("updating quote #%d", id), err)
+	}
+	return call.Reply("edited", replyData{"id": id})
+}
+
+func (h *CommandHandler) runAuthor(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	newAuthor := strings.TrimSpace(call.Text("author"))
+	if err := h.store.UpdateAuthor(ctx, call.Channel, id, newAuthor, call.Sender.Name); err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("changing author for quote #%d", id), err)
+	}
+	return call.Reply("author_changed", replyData{"id": id, "author": newAuthor})
+}
+
+func (h *CommandHandler) runTag(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	added, err := h.store.AddTags(ctx, call.Channel, id, strings.Fields(call.Text("tag...")), call.Sender.Name)
+	if err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("tagging quote #%d", id), err)
+	}
+	if len(added) == 0 {
+		return call.Reply("tagged_none", replyData{"id": id})
+	}
+	return call.Reply("tagged", replyData{"id": id, "tags": formatTags(added)})
+}
+
+func (h *CommandHandler) runUntag(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	removed, err := h.store.RemoveTags(ctx, call.Channel, id, strings.Fields(call.Text("tag...")), call.Sender.Name)
+	if err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("untagging quote #%d", id), err)
+	}
+	if len(removed) == 0 {
+		return call.Reply("untagged_none", replyData{"id": id})
+	}
+	return call.Reply("untagged", replyData{"id": id, "tags": formatTags(removed)})
+}
+
+func (h *CommandHandler) runTags(ctx context.Context, call *CommandCall) []string {
+	if call.Has("id") {
+		id := call.Int("id")
+		tags, err := h.store.TagsFor(ctx, call.Channel, id)
 		if err != nil {
-			return []string{"Invalid quote ID."}
+			return h.replyError(call.Channel, fmt.Sprintf("fetching tags for quote #%d", id), err)
 		}
-		if err := h.store.Delete(ctx, id); err != nil {
-			return []string{fmt.Sprintf("Error deleting quote #%d: %v", id, err)}
+		if len(tags) == 0 {
+			return call.Reply("quote_tags_none", replyData{"id": id})
 		}
-		return []string{fmt.Sprintf("Quote #%d deleted.", id)}
-	case "edit":
-		if len(parts) < 4 {
-			return []string{"Usage: !quote edit <id> | <new quote text>"}
+		return call.Reply("quote_tags", replyData{"id": id, "tags": formatTags(tags)})
+	}
+	counts, err := h.store.TopTags(ctx, call.Channel, 10)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("tags_none", nil)
 		}
-		if !isMod {
-			return []string{"Only Twitch moderators can edit quotes."}
+		return h.replyError(call.Channel, "listing tags", err)
+	}
+	return call.Reply("tags", replyData{"tags": formatTagCounts(counts)})
+}
+
+func (h *CommandHandler) runHistory(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	revisions, err := h.store.History(ctx, call.Channel, id, 3)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return call.Reply("history_none", replyData{"id": id})
 		}
-		id, err := strconv.Atoi(parts[2])
-		if err != nil {
-			return []string{"Invalid quote ID."}
+		return h.replyError(call.Channel, fmt.Sprintf("fetching history for quote #%d", id), err)
+	}
+	var respParts []string
+	now := time.Now()
+	for _, rev := range revisions {
+		respParts = append(respParts, describeRevision(rev, now))
+	}
+	return call.Reply("history", replyData{"id": id, "history": strings.Join(respParts, " | ")})
+}
+
+func (h *CommandHandler) runApprove(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	added, err := h.store.Approve(ctx, call.Channel, id, call.Sender.Name)
+	if err != nil {
+		if reply, ok := h.duplicateReply(call.Channel, err); ok {
+			return call.Reply("approve_duplicate", replyData{"id": id, "reason": reply})
 		}
-		newText := strings.Join(parts[3:], " ")
-		newText = strings.TrimSpace(strings.TrimPrefix(newText, "|"))
-		if newText == "" {
-			return []string{"Usage: !quote edit <id> | <new quote text>"}
+		return h.replyError(call.Channel, fmt.Sprintf("approving pending #%d", id), err)
+	}
+	return call.Reply("approved", replyData{"id": id, "quote_id": added.ID, "similar_id": similarID(added)})
+}
+
+func (h *CommandHandler) runReject(ctx context.Context, call *CommandCall) []string {
+	id := call.Int("id")
+	if err := h.store.Reject(ctx, call.Channel, id, call.Sender.Name, call.Text("reason")); err != nil {
+		return h.replyError(call.Channel, fmt.Sprintf("rejecting pending #%d", id), err)
+	}
+	return call.Reply("rejected", replyData{"id": id})
+}
+
+// duplicateReply returns channel's reply for a submission that is already saved or already waiting
+// for approval, and false when err is not a *DuplicateQuoteError.
+func (h *CommandHandler) duplicateReply(channel string, err error) (string, bool) {
+	var dup *DuplicateQuoteError
+	if !errors.As(err, &dup) {
+		return "", false
+	}
+	if dup.Pending {
+		return h.reply(channel, "duplicate_pending", replyData{"id": dup.ExistingID}), true
+	}
+	return h.reply(channel, "duplicate", replyData{"id": dup.ExistingID}), true
+}
+
+// similarID returns the number of the quote a newly added one closely resembles, or 0 when there
+// is none.
+func similarID(added AddResult) int {
+	if added.Similar == nil {
+		return 0
+	}
+	return added.Similar.ID
+}
+
+// reply renders channel's reply called name with data.
+func (h *CommandHandler) reply(channel, name string, data replyData) string {
+	return h.channels.For(channel).reply(name, data)
+}
+
+// replyError renders channel's reply to an action, such as "adding quote", that failed with err.
+func (h *CommandHandler) replyError(channel, action string, err error) []string {
+	return []string{h.reply(channel, "error", replyData{"action": action, "error": err.Error()})}
+}
+
+// chatPendingLimit is how many submissions !quote pending lists.
+const chatPendingLimit = 5
+
+// pending replies with the oldest submissions waiting in channel's approval queue.
+func (h *CommandHandler) pending(ctx context.Context, channel string) []string {
+	pending, total, err := h.store.Pending(ctx, channel, chatPendingLimit)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return []string{h.reply(channel, "pending_none", nil)}
+		}
+		return h.replyError(channel, "listing pending quotes", err)
+	}
+	// formatListPage works on quotes, so carry each submission's ID and submitter in a Quote.
+	quotes := make([]Quote, len(pending))
+	for i, p := range pending {
+		quotes[i] = Quote{ID: p.ID, Text: p.Text, Author: p.Author, SubmittedBy: p.SubmittedBy}
+	}
+	settings := h.channels.For(channel)
+	message := func(items string) string {
+		return settings.reply("pending", replyData{"total": total, "quotes": items})
+	}
+	return []string{formatListPage(message, quotes, twitchMessageLimit, settings.formatPendingQuote)}
+}
+
+// formatPendingQuote formats a submission carried in a Quote (see pending) with who submitted it.
+func (s ChannelSettings) formatPendingQuote(q Quote) string {
+	return s.replyQuote("quote_pending", q, replyData{"submitter": emptyPlaceholder(q.SubmittedBy)})
+}
+
+// recordView counts q as shown in channel. Failures are logged so they never hold back a reply.
+func (h *CommandHandler) recordView(ctx context.Context, channel string, q Quote) {
+	if err := h.store.RecordView(ctx, channel, q.ID); err != nil {
+		log.Printf("Error recording view of quote #%d in #%s: %v", q.ID, channel, err)
+	}
+}
+
+// chatStatsLimit is how many authors and quotes !quote stats names.
+const chatStatsLimit = 3
+
+// stats replies with a one-message summary of channel's quotes, their authors and views.
+func (h *CommandHandler) stats(ctx context.Context, channel string) []string {
+	stats, err := h.store.Stats(ctx, channel, chatStatsLimit)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			return []string{h.reply(channel, "empty", nil)}
 		}
-		if err := h.store.UpdateText(ctx, id, newText); err != nil {
-			return []string{fmt.Sprintf("Error updating quote #%d: %v", id, err)}
+		return h.replyError(channel, "gathering stats", err)
+	}
+	authors := make([]string, len(stats.TopAuthors))
+	for i, ac := range stats.TopAuthors {
+		authors[i] = fmt.Sprintf("%s (%d)", ac.Name, ac.Count)
+	}
+	shown := make([]string, len(stats.MostShown))
+	for i, q := range stats.MostShown {
+		shown[i] = fmt.Sprintf("#%d (%d view%s)", q.ID, q.Views, pluralSuffix(q.Views))
+	}
+	response := h.reply(channel, "stats", replyData{
+		"total":       stats.Total,
+		"authors":     stats.Authors,
+		"views":       stats.Views,
+		"never_shown": stats.NeverShown,
+		"top_authors": strings.Join(authors, ", "),
+		"most_shown":  strings.Join(shown, ", "),
+	})
+	return []string{truncate(response, twitchMessageLimit-3)}
+}
+
+// lookupStreamInfo returns what channel is currently streaming. Lookups get at most
+// streamInfoTimeout of ctx's deadline; failures are logged and reported as empty info so they
+// never block adding a quote.
+func (h *CommandHandler) lookupStreamInfo(ctx context.Context, channel string) StreamInfo {
+	if channel == "" {
+		return StreamInfo{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, streamInfoTimeout)
+	defer cancel()
+	info, err := h.streamInfo.StreamInfo(ctx, channel)
+	if err != nil {
+		log.Printf("Error looking up stream info for #%s: %v", channel, err)
+		return StreamInfo{}
+	}
+	return info
+}
+
+// parseRandomOptions parses the "fresh" keyword and "#tag" and "game:<name>" filters on top of the
+// configured settings. A game name runs until the next #tag, so "game:elden ring #rage" works
+// without quoting.
+func parseRandomOptions(args []string, settings RandomSettings) (RandomOptions, bool) {
+	opts := RandomOptions{Weight: settings.Weight}
+	var game []string
+	inGame := false
+	for _, arg := range args {
+		lower := strings.ToLower(arg)
+		switch {
+		case strings.HasPrefix(arg, "#"):
+			if opts.Tag != "" {
+				return RandomOptions{}, false
+			}
+			opts.Tag = arg
+			inGame = false
+		case strings.HasPrefix(lower, "game:"):
+			inGame = true
+			if value := arg[len("game:"):]; value != "" {
+				game = append(game, value)
+			}
+		case inGame:
+			game = append(game, arg)
+		case lower == "fresh":
+			opts.FreshWithin = settings.FreshWithin
+		default:
+			return RandomOptions{}, false
 		}
-		return []string{fmt.Sprintf("Quote #%d updated.", id)}
-	case "setauthor", "author", "reauthor":
-		if len(parts) < 4 {
-			return []string{"Usage: !quote author <id> <new author>"}
+	}
+	opts.Game = strings.Join(game, " ")
+	return opts, true
+}
+
+// chatListPageSize is how many quotes !quote list shows per page.
+const chatListPageSize = 5
+
+// twitchMessageLimit is the longest message Twitch chat accepts.
+const twitchMessageLimit = 500
+
+// listRequest is a parsed "[page N] [sort <field>] [desc] [by <author> | submitted-by <user>]"
+// list command.
+type listRequest struct {
+	Page        int
+	Sort        ListSort
+	Descending  bool
+	Author      string
+	SubmittedBy string
+}
+
+// parseListArgs parses the arguments of a list command. A bare number is taken as the page, and
+// everything after "by" or "submitted-by" is the name so names with spaces need no quoting.
+func parseListArgs(args []string) (listRequest, error) {
+	req := listRequest{Page: 1, Sort: ListSortID}
+	for i := 0; i < len(args); i++ {
+		switch lower := strings.ToLower(args[i]); {
+		case lower == "by" || lower == "submitted-by":
+			name := userArg(args[i+1:])
+			if name == "" {
+				return listRequest{}, fmt.Errorf("missing name after %s", lower)
+			}
+			if lower == "by" {
+				req.Author = name
+			} else {
+				req.SubmittedBy = name
+			}
+			return req, nil
+		case lower == "page" && i+1 < len(args):
+			i++
+			page, err := strconv.Atoi(args[i])
+			if err != nil || page < 1 {
+				return listRequest{}, fmt.Errorf("invalid page %q", args[i])
+			}
+			req.Page = page
+		case lower == "sort" && i+1 < len(args):
+			i++
+			sort, err := parseListSort(args[i])
+			if err != nil {
+				return listRequest{}, err
+			}
+			req.Sort = sort
+		case lower == "desc" || lower == "asc":
+			req.Descending = lower == "desc"
+		default:
+			page, err := strconv.Atoi(args[i])
+			if err != nil || page < 1 {
+				return listRequest{}, fmt.Errorf("unexpected %q", args[i])
+			}
+			req.Page = page
 		}
-		if !isMod {
-			return []string{"Only Twitch moderators can change quote authors."}
+	}
+	return req, nil
+}
+
+// options returns the ListOptions selecting req's page when pages hold pageSize quotes.
+func (req listRequest) options(pageSize int) ListOptions {
+	return ListOptions{
+		Sort:        req.Sort,
+		Descending:  req.Descending,
+		Author:      req.Author,
+		SubmittedBy: req.SubmittedBy,
+		Limit:       pageSize,
+		Offset:      (req.Page - 1) * pageSize,
+	}
+}
+
+// describe names the quotes req selects, e.g. "Quotes by nick", for use in replies.
+func (req listRequest) describe() string {
+	switch {
+	case req.Author != "":
+		return "Quotes by " + req.Author
+	case req.SubmittedBy != "":
+		return "Quotes submitted by " + req.SubmittedBy
+	}
+	return "Quotes"
+}
+
+// userArg joins args into a single name, dropping the "@" chat users put in front of mentions.
+func userArg(args []string) string {
+	return strings.TrimPrefix(strings.TrimSpace(strings.Join(args, " ")), "@")
+}
+
+// list replies with one page of quotes from channel, shortening quote text so the page fits in a
+// single chat message.
+func (h *CommandHandler) list(ctx context.Context, channel string, req listRequest) []string {
+	page, err := h.store.ListPage(ctx, channel, req.options(chatListPageSize))
+	if err != nil {
+		return h.replyError(channel, "listing quotes", err)
+	}
+	settings := h.channels.For(channel)
+	filter := ""
+	if req.Author != "" || req.SubmittedBy != "" {
+		filter = req.describe()
+	}
+	if page.Total == 0 {
+		return []string{settings.reply("list_none", replyData{"filter": filter})}
+	}
+	pages := pageCount(page.Total, chatListPageSize)
+	if len(page.Quotes) == 0 {
+		return []string{settings.reply("list_beyond", replyData{"pages": pages})}
+	}
+	message := func(items string) string {
+		return settings.reply("list", replyData{"filter": filter, "page": req.Page, "pages": pages, "quotes": items})
+	}
+	return []string{formatListPage(message, page.Quotes, twitchMessageLimit, settings.FormatQuote)}
+}
+
+// formatListPage renders message with quotes formatted by format and joined with " | ", truncating
+// each quote's text just enough for the result to stay within limit bytes.
+func formatListPage(message func(items string) string, quotes []Quote, limit int, format func(Quote) string) string {
+	render := func(textLimit int) string {
+		parts := make([]string, len(quotes))
+		for i, q := range quotes {
+			q.Text = truncate(q.Text, textLimit)
+			parts[i] = format(q)
 		}
-		id, err := strconv.Atoi(parts[2])
-		if err != nil {
-			return []string{"Invalid quote ID."}
+		return message(strings.Join(parts, " | "))
+	}
+	for textLimit := 200; textLimit > 10; textLimit -= 10 {
+		if line := render(textLimit); len(line) <= limit {
+			return line
 		}
-		newAuthor := strings.TrimSpace(strings.Join(parts[3:], " "))
-		if newAuthor == "" {
-			return []string{"Usage: !quote author <id> <new author>"}
+	}
+	return truncate(render(10), limit-3)
+}
+
+// leaderboard replies with the limit highest-scoring quotes in channel, or the lowest-scoring ones
+// when worst is set. Quotes nobody has voted up (or down) are left out.
+func (h *CommandHandler) leaderboard(ctx context.Context, channel string, limit int, worst bool) []string {
+	page, err := h.store.ListPage(ctx, channel, ListOptions{Sort: ListSortRating, Descending: !worst, Limit: limit})
+	if err != nil {
+		return h.replyError(channel, "ranking quotes", err)
+	}
+	var ranked []Quote
+	for _, q := range page.Quotes {
+		if (!worst && q.Score > 0) || (worst && q.Score < 0) {
+			ranked = append(ranked, q)
 		}
-		if err := h.store.UpdateAuthor(ctx, id, newAuthor); err != nil {
-			return []string{fmt.Sprintf("Error changing author for quote #%d: %v", id, err)}
+	}
+	name := "top"
+	if worst {
+		name = "worst"
+	}
+	settings := h.channels.For(channel)
+	if len(ranked) == 0 {
+		return []string{settings.reply(name+"_none", nil)}
+	}
+	message := func(items string) string {
+		return settings.reply(name, replyData{"quotes": items})
+	}
+	return []string{formatListPage(message, ranked, twitchMessageLimit, func(q Quote) string {
+		return settings.replyQuote("quote_scored", q, nil)
+	})}
+}
+
+// pageCount returns how many pages of pageSize items it takes to show total items.
+func pageCount(total, pageSize int) int {
+	return (total + pageSize - 1) / pageSize
+}
+
+// random replies with a random quote from channel matching opts.
+func (h *CommandHandler) random(ctx context.Context, channel string, opts RandomOptions) []string {
+	quote, err := h.store.Random(ctx, channel, opts)
+	if err != nil {
+		if errors.Is(err, ErrNoQuotes) {
+			switch {
+			case opts.Tag != "" || opts.Game != "":
+				return []string{h.reply(channel, "random_no_match", replyData{"tag": opts.Tag, "game": opts.Game})}
+			case opts.Author != "":
+				return []string{h.reply(channel, "by_none", replyData{"author": opts.Author})}
+			case opts.SubmittedBy != "":
+				return []string{h.reply(channel, "submitted_none", replyData{"submitter": opts.SubmittedBy})}
+			}
+			return []string{h.reply(channel, "random_empty", nil)}
 		}
-		return []string{fmt.Sprintf("Quote #%d author updated to %s.", id, newAuthor)}
-	default:
-		return []string{printHelp()}
+		return h.replyError(channel, "fetching quote", err)
 	}
+	h.recordView(ctx, channel, *quote)
+	return []string{h.channels.For(channel).replyQuote("random", *quote, nil)}
 }
 
-// formatQuote formats a Quote as a single-line string in the form `#<ID>: "<Text>" - <Author>`.
+// formatStreamContext describes the game and stream title captured with a quote, or returns "" when neither is known.
+func formatStreamContext(q Quote) string {
+	switch {
+	case q.Game != "" && q.StreamTitle != "":
+		return fmt.Sprintf("(playing %s, \"%s\")", q.Game, truncate(q.StreamTitle, 50))
+	case q.Game != "":
+		return fmt.Sprintf("(playing %s)", q.Game)
+	case q.StreamTitle != "":
+		return fmt.Sprintf("(\"%s\")", truncate(q.StreamTitle, 50))
+	}
+	return ""
+}
+
+// formatSubmitter describes who added a quote and from where, or returns "" when that was not recorded.
+func formatSubmitter(q Quote) string {
+	switch {
+	case q.SubmittedBy == "":
+		return ""
+	case q.Platform != "":
+		return fmt.Sprintf("submitted by %s via %s", q.SubmittedBy, q.Platform)
+	}
+	return "submitted by " + q.SubmittedBy
+}
+
+// formatTagCounts renders tag usage counts as "#tag (n)" separated by commas.
+func formatTagCounts(counts []TagCount) string {
+	parts := make([]string, len(counts))
+	for i, tc := range counts {
+		parts[i] = fmt.Sprintf("#%s (%d)", tc.Name, tc.Count)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatQuote formats a Quote with the default quote format, a single line in the form
+// `#<ID>: "<Text>" - <Author>`.
 func formatQuote(q Quote) string {
-	return fmt.Sprintf("#%d: \"%s\" - %s", q.ID, q.Text, q.Author)
-}
-
-// printHelp returns the usage help text for the !quote command and its subcommands.
-func printHelp() string {
-	return `Usage:
-!quote              - Return a random quote.
-!quote add <quote>  - Add a new quote (author will be the sender).
-!quote add <author> | <quote> - Add a quote for another author.
-!quote search <term> - Search for a quote.
-!quote get <id>     - Get a specific quote by ID.
-!quote list         - List the first 5 quotes.
-!quote latest       - Show the most recently added quote.
-!quote count        - Show how many quotes are stored.
-!quote delete <id>  - Delete a quote (Twitch moderator only).
-!quote edit <id> | <quote> - Update quote text (Twitch moderator only).
-!quote author <id> <author> - Change quote author (Twitch moderator only).
-!quote help        - Show this help message.`
+	return ChannelSettings{}.FormatQuote(q)
+}
+
+// formatTrashedQuote formats a deleted quote with who deleted it, truncating the text to keep trash
+// listings within a single chat message.
+func (s ChannelSettings) formatTrashedQuote(q Quote) string {
+	q.Text = truncate(q.Text, 60)
+	return s.replyQuote("quote_trashed", q, replyData{"deleted_by": emptyPlaceholder(q.DeletedBy)})
 }
 
 // pluralize returns the singular form when count is 1 and the plural form otherwise.
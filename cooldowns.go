@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cooldownSaveInterval is how often cooldowns are written to the cooldown file while they change.
+const cooldownSaveInterval = 30 * time.Second
+
+// cooldownPruneInterval is how often take drops the cooldowns that have ended, so the tracker
+// stays small whether or not cooldowns are saved.
+const cooldownPruneInterval = time.Minute
+
+// CooldownConfig is the configured cooldown of a subcommand in seconds: across every channel, in
+// the channel, and for each user in the channel. Zero means no cooldown at that scope.
+type CooldownConfig struct {
+	GlobalSeconds  int `json:"global_seconds,omitempty"`
+	ChannelSeconds int `json:"channel_seconds,omitempty"`
+	UserSeconds    int `json:"user_seconds,omitempty"`
+}
+
+// cooldownLimits is a CooldownConfig as durations.
+type cooldownLimits struct {
+	global, channel, user time.Duration
+}
+
+// CommandCooldowns is how often subcommands may run in a channel. The zero value has no
+// cooldowns.
+type CommandCooldowns struct {
+	// Reply answers a command that is still cooling down with how long is left instead of
+	// dropping it silently.
+	Reply bool
+	// ExemptRole is the lowest role whose commands skip cooldowns and do not start them.
+	ExemptRole Role
+	// limits holds the cooldowns by subcommand name, with "*" for subcommands not listed.
+	limits map[string]cooldownLimits
+}
+
+// parseCommandCooldowns validates a channel's cooldown settings against the subcommands in
+// registry. reply is "silent" (the default) or "wait"; an empty exempt role means moderators.
+func parseCommandCooldowns(cooldowns map[string]CooldownConfig, reply, exempt string, registry *CommandRegistry) (CommandCooldowns, error) {
+	c := CommandCooldowns{ExemptRole: RoleModerator, limits: make(map[string]cooldownLimits)}
+	switch strings.ToLower(strings.TrimSpace(reply)) {
+	case "", "silent":
+	case "wait", "reply":
+		c.Reply = true
+	default:
+		return CommandCooldowns{}, fmt.Errorf("unknown cooldown reply %q (use silent or wait)", reply)
+	}
+	if strings.TrimSpace(exempt) != "" {
+		role, err := parseRole(exempt)
+		if err != nil {
+			return CommandCooldowns{}, fmt.Errorf("cooldown_exempt_role: %w", err)
+		}
+		c.ExemptRole = role
+	}
+	for name, cfg := range cooldowns {
+		if name = strings.TrimSpace(name); name != allCommands {
+			var err error
+			if name, err = canonicalCommand(registry, "command_cooldowns", name); err != nil {
+				return CommandCooldowns{}, err
+			}
+		}
+		if cfg.GlobalSeconds < 0 || cfg.ChannelSeconds < 0 || cfg.UserSeconds < 0 {
+			return CommandCooldowns{}, fmt.Errorf("command_cooldowns: %s: cooldowns must not be negative", name)
+		}
+		c.limits[name] = cooldownLimits{
+			global:  time.Duration(cfg.GlobalSeconds) * time.Second,
+			channel: time.Duration(cfg.ChannelSeconds) * time.Second,
+			user:    time.Duration(cfg.UserSeconds) * time.Second,
+		}
+	}
+	return c, nil
+}
+
+// limitsFor returns the cooldowns of the subcommand called name, falling back to those for "*".
+func (c CommandCooldowns) limitsFor(name string) cooldownLimits {
+	if limits, ok := c.limits[name]; ok {
+		return limits
+	}
+	return c.limits[allCommands]
+}
+
+// cooldownTracker remembers until when each cooldown runs. Keys name the scope:
+// "global/<command>", "channel/<channel>/<command>" and "user/<channel>/<command>/<user>".
+type cooldownTracker struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+	// warned holds, for keys whose remaining wait was already announced, the end of the cooldown
+	// it was announced for, so a raid gets one reply rather than one per message.
+	warned map[string]time.Time
+	dirty  bool
+	// pruned is when take last dropped the ended cooldowns.
+	pruned time.Time
+}
+
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{until: make(map[string]time.Time), warned: make(map[string]time.Time)}
+}
+
+// cooldownKey is one cooldown a command is subject to.
+type cooldownKey struct {
+	key      string
+	duration time.Duration
+}
+
+// take starts the cooldowns in keys at now unless one of them is still running. Otherwise it
+// returns the longest remaining wait, and whether that wait has not been announced yet.
+func (t *cooldownTracker) take(now time.Time, keys []cooldownKey) (wait time.Duration, announce bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now.Sub(t.pruned) >= cooldownPruneInterval {
+		t.pruneLocked(now)
+		t.pruned = now
+	}
+	var blocking string
+	for _, k := range keys {
+		if left := t.until[k.key].Sub(now); left > wait {
+			wait, blocking = left, k.key
+		}
+	}
+	if wait > 0 {
+		until := t.until[blocking]
+		announce = !t.warned[blocking].Equal(until)
+		t.warned[blocking] = until
+		return wait, announce
+	}
+	for _, k := range keys {
+		t.until[k.key] = now.Add(k.duration)
+		delete(t.warned, k.key)
+	}
+	t.dirty = true
+	return 0, false
+}
+
+// pruneLocked drops the cooldowns that ended before now.
+func (t *cooldownTracker) pruneLocked(now time.Time) {
+	for key, until := range t.until {
+		if !until.After(now) {
+			delete(t.until, key)
+			delete(t.warned, key)
+		}
+	}
+}
+
+// load adds the cooldowns saved at path that are still running. A missing file is not an error.
+func (t *cooldownTracker) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved map[string]time.Time
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, until := range saved {
+		if until.After(now) && until.After(t.until[key]) {
+			t.until[key] = until
+		}
+	}
+	return nil
+}
+
+// save writes the running cooldowns to path if they changed since the last save, replacing the
+// file atomically.
+func (t *cooldownTracker) save(path string) error {
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	running := make(map[string]time.Time, len(t.until))
+	for key, until := range t.until {
+		if until.After(now) {
+			running[key] = until
+		}
+	}
+	data, err := json.MarshalIndent(running, "", "  ")
+	t.dirty = false
+	t.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cooldowns-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cooldown checks the cooldowns of the subcommand described by info for sender in channel and
+// starts them when none is running. While one is running it returns false and the replies to send
+// instead of running the subcommand, which are empty unless the channel announces the wait.
+func (h *CommandHandler) cooldown(channel string, sender Sender, info CommandInfo, cooldowns CommandCooldowns) ([]string, bool) {
+	limits := cooldowns.limitsFor(info.Name)
+	if sender.Role >= cooldowns.ExemptRole || limits == (cooldownLimits{}) {
+		return nil, true
+	}
+	channel = normalizeChannel(channel)
+	user := strings.ToLower(firstNonEmpty(sender.ID, sender.Login, sender.Name))
+	var keys []cooldownKey
+	if limits.global > 0 {
+		keys = append(keys, cooldownKey{"global/" + info.Name, limits.global})
+	}
+	if limits.channel > 0 {
+		keys = append(keys, cooldownKey{"channel/" + channel + "/" + info.Name, limits.channel})
+	}
+	if limits.user > 0 && user != "" {
+		keys = append(keys, cooldownKey{"user/" + channel + "/" + info.Name + "/" + sender.Platform + ":" + user, limits.user})
+	}
+
+	wait, announce := h.cooldowns.take(time.Now(), keys)
+	if wait <= 0 {
+		return nil, true
+	}
+	if !cooldowns.Reply || !announce {
+		return nil, false
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	return []string{h.reply(channel, "cooldown", replyData{"seconds": seconds, "command": info.Name, "user": sender.Name})}, false
+}
+
+// LoadCooldowns restores the cooldowns saved at path by SaveCooldowns, so a restart does not
+// reset them.
+func (h *CommandHandler) LoadCooldowns(path string) error {
+	return h.cooldowns.load(path)
+}
+
+// SaveCooldowns writes the running cooldowns to path.
+func (h *CommandHandler) SaveCooldowns(path string) error {
+	return h.cooldowns.save(path)
+}
+
+// runCooldownSaver writes the handler's cooldowns to path every cooldownSaveInterval until ctx is
+// canceled; the caller saves them a last time on shutdown.
+func runCooldownSaver(ctx context.Context, h *CommandHandler, path string) {
+	ticker := time.NewTicker(cooldownSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.SaveCooldowns(path); err != nil {
+				log.Printf("Error saving cooldowns to %s: %v", path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nearDuplicateThreshold is the similarity from which a new quote is reported as looking like an
+// existing one, and the default threshold of the dedupe command.
+const nearDuplicateThreshold = 0.8
+
+// recentQuoteWindow is how many of a channel's newest quotes a new quote is compared against for
+// near-duplicates. Exact duplicates are found among all live quotes through the fingerprint index.
+const recentQuoteWindow = 200
+
+// DuplicateQuoteError is returned by Add, Restore and Revert when another live quote with the same
+// fingerprint already exists, and by Submit when one is live or waiting for approval. Pending is set in the latter case, and
+// ExistingID is then the submission's ID in the approval queue.
+type DuplicateQuoteError struct {
+	ExistingID int
+	Pending    bool
+}
+
+func (e *DuplicateQuoteError) Error() string {
+	if e.Pending {
+		return fmt.Sprintf("already waiting for approval as pending #%d", e.ExistingID)
+	}
+	return fmt.Sprintf("already saved as #%d", e.ExistingID)
+}
+
+// AddResult describes a newly added quote. Similar is set when the new quote closely resembles one
+// of the channel's recent quotes without being an exact duplicate.
+type AddResult struct {
+	ID      int
+	Similar *SimilarQuote
+}
+
+// SimilarQuote is an existing quote together with how similar it is to another text, from 0 to 1.
+type SimilarQuote struct {
+	Quote
+	Score float64
+}
+
+// commonEmotes are widely used Twitch, BTTV and 7TV emote names. They are matched case-sensitively
+// so ordinary words such as "clap" are kept.
+var commonEmotes = map[string]bool{
+	"4Head": true, "BibleThump": true, "BloodTrail": true, "Clap": true, "CoolCat": true,
+	"CoolStoryBob": true, "Copium": true, "DansGame": true, "EZ": true, "FeelsBadMan": true,
+	"FeelsGoodMan": true, "HeyGuys": true, "Jebaited": true, "KEKW": true, "Kappa": true,
+	"Keepo": true, "Kreygasm": true, "LUL": true, "LULW": true, "NotLikeThis": true,
+	"OMEGALUL": true, "PJSalt": true, "PauseChamp": true, "PepeHands": true, "PepeLaugh": true,
+	"Pog": true, "PogChamp": true, "POGGERS": true, "PogU": true, "ResidentSleeper": true,
+	"Sadge": true, "SeemsGood": true, "SwiftRage": true, "TriHard": true, "VoHiYo": true,
+	"WeirdChamp": true, "WutFace": true, "catJAM": true, "modCheck": true, "monkaS": true,
+	"monkaW": true, "peepoHappy": true,
+}
+
+// quoteFingerprint normalizes quote text for duplicate detection: emotes, emoji and punctuation are
+// dropped, apostrophes are removed so "don't" matches "dont", and the remaining words are
+// lower-cased and joined by single spaces. Text made only of emotes has an empty fingerprint.
+func quoteFingerprint(text string) string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if commonEmotes[strings.Trim(word, ".,!?:;\"'()")] {
+			continue
+		}
+		word = strings.ReplaceAll(word, "'", "")
+		word = strings.ReplaceAll(word, "’", "")
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			words = append(words, strings.ToLower(part))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// bigrams counts the character pairs of a fingerprint.
+func bigrams(fingerprint string) map[string]int {
+	runes := []rune(fingerprint)
+	counts := make(map[string]int, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		counts[string(runes[i:i+2])]++
+	}
+	return counts
+}
+
+// diceSimilarity is the Sørensen–Dice coefficient of two bigram multisets: 1 for identical
+// fingerprints and 0 when they share no character pairs.
+func diceSimilarity(a, b map[string]int) float64 {
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	shared := 0
+	for pair, n := range a {
+		shared += min(n, b[pair])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// closestQuote returns the candidate most similar to fingerprint when it scores at least
+// nearDuplicateThreshold, or nil otherwise.
+func closestQuote(fingerprint string, candidates []Quote) *SimilarQuote {
+	if fingerprint == "" {
+		return nil
+	}
+	target := bigrams(fingerprint)
+	var best *SimilarQuote
+	for _, q := range candidates {
+		score := diceSimilarity(target, bigrams(quoteFingerprint(q.Text)))
+		if score >= nearDuplicateThreshold && (best == nil || score > best.Score) {
+			best = &SimilarQuote{Quote: q, Score: score}
+		}
+	}
+	return best
+}
+
+// duplicateQuery finds the lowest-numbered live quote in a channel with a given fingerprint. Without
+// the INDEXED BY hint SQLite walks the channel in channel_seq order to satisfy the ORDER BY, which
+// makes checking every row of a large batch quadratic.
+const duplicateQuery = "SELECT channel_seq FROM quotes INDEXED BY idx_quotes_fingerprint WHERE " + liveInChannel + " AND quotes.fingerprint = ? ORDER BY channel_seq LIMIT 1"
+
+// duplicateOtherQuery is duplicateQuery ignoring the quote with a given row ID, for changes to an
+// existing quote's text.
+const duplicateOtherQuery = "SELECT channel_seq FROM quotes INDEXED BY idx_quotes_fingerprint WHERE " + liveInChannel + " AND quotes.fingerprint = ? AND quotes.id != ? ORDER BY channel_seq LIMIT 1"
+
+// duplicateTx returns an error naming the live quote in channel with the same fingerprint, if any.
+func duplicateTx(ctx context.Context, tx *sql.Tx, channel, fingerprint string) error {
+	return duplicateOtherTx(ctx, tx, channel, fingerprint, 0)
+}
+
+// duplicateOtherTx is duplicateTx ignoring the quote with row ID except.
+func duplicateOtherTx(ctx context.Context, tx *sql.Tx, channel, fingerprint string, except int64) error {
+	if fingerprint == "" {
+		return nil
+	}
+	var existing int
+	err := tx.QueryRowContext(ctx, duplicateOtherQuery, channel, fingerprint, except).Scan(&existing)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("checking for duplicates: %w", err)
+	}
+	return &DuplicateQuoteError{ExistingID: existing}
+}
+
+// similarRecentTx compares fingerprint against the newest live quotes in channel.
+func similarRecentTx(ctx context.Context, tx *sql.Tx, channel, fingerprint string) (*SimilarQuote, error) {
+	if fingerprint == "" {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" ORDER BY quotes.id DESC LIMIT ?", channel, recentQuoteWindow)
+	if err != nil {
+		return nil, fmt.Errorf("loading recent quotes: %w", err)
+	}
+	recent, err := scanQuotes(rows)
+	if err != nil && !errors.Is(err, ErrNoQuotes) {
+		return nil, err
+	}
+	return closestQuote(fingerprint, recent), nil
+}
+
+// backfillFingerprints stores the fingerprint of every existing quote.
+func backfillFingerprints(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, text FROM quotes")
+	if err != nil {
+		return err
+	}
+	fingerprints := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		fingerprints[id] = quoteFingerprint(text)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, fingerprint := range fingerprints {
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET fingerprint = ? WHERE id = ?", fingerprint, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicatePair is a proposed merge found by findDuplicates: Drop repeats Keep, the older quote.
+type duplicatePair struct {
+	Keep, Drop Quote
+	Score      float64
+}
+
+// findDuplicates compares every pair of quotes and returns those at least threshold similar, most
+// similar first. Each quote is proposed for dropping at most once.
+func findDuplicates(quotes []Quote, threshold float64) []duplicatePair {
+	type entry struct {
+		quote       Quote
+		fingerprint string
+		bigrams     map[string]int
+		size        int
+	}
+	entries := make([]entry, 0, len(quotes))
+	for _, q := range quotes {
+		fp := quoteFingerprint(q.Text)
+		if fp == "" {
+			continue
+		}
+		grams := bigrams(fp)
+		entries = append(entries, entry{quote: q, fingerprint: fp, bigrams: grams, size: len([]rune(fp)) - 1})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].quote.ID < entries[j].quote.ID })
+
+	var pairs []duplicatePair
+	dropped := make(map[int]bool)
+	for j := range entries {
+		var best *duplicatePair
+		for i := 0; i < j; i++ {
+			a, b := entries[i], entries[j]
+			if dropped[a.quote.ID] {
+				continue
+			}
+			// Dice can never exceed 2*min/(a+b), so skip pairs whose lengths differ too much.
+			if bound := 2 * float64(min(a.size, b.size)) / float64(a.size+b.size); a.fingerprint != b.fingerprint && bound < threshold {
+				continue
+			}
+			score := 1.0
+			if a.fingerprint != b.fingerprint {
+				score = diceSimilarity(a.bigrams, b.bigrams)
+			}
+			if score >= threshold && (best == nil || score > best.Score) {
+				best = &duplicatePair{Keep: a.quote, Drop: b.quote, Score: score}
+			}
+		}
+		if best != nil {
+			dropped[best.Drop.ID] = true
+			pairs = append(pairs, *best)
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	return pairs
+}
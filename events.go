@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ChangeType is the kind of change a ChangeEvent reports.
+type ChangeType string
+
+const (
+	// ChangeAdded reports a new quote, including one approved from the queue.
+	ChangeAdded ChangeType = "added"
+	// ChangeUpdated reports an edit, author change, revert or tag change to a live quote.
+	ChangeUpdated ChangeType = "updated"
+	// ChangeDeleted reports a quote moved to the trash.
+	ChangeDeleted ChangeType = "deleted"
+	// ChangeRestored reports a quote restored from the trash.
+	ChangeRestored ChangeType = "restored"
+)
+
+// ChangeEvent is one change to a quote, delivered to subscribers in the order the changes were
+// made. ID is the change's revision in the quote's history, so events can be matched against
+// History; Action is that revision's action. Text and Author are the quote's state after the
+// change.
+type ChangeEvent struct {
+	ID      int        `json:"id"`
+	Type    ChangeType `json:"type"`
+	Channel string     `json:"channel"`
+	QuoteID int        `json:"quote_id"`
+	Actor   string     `json:"actor"`
+	Action  string     `json:"action"`
+	Text    string     `json:"text"`
+	Author  string     `json:"author"`
+	At      time.Time  `json:"at"`
+}
+
+// changeEvent returns the event rev reports. Revisions that do not change what viewers see, such
+// as purges of already deleted quotes and the approval note that follows an add, report none.
+func changeEvent(rev Revision) (ChangeEvent, bool) {
+	var kind ChangeType
+	switch rev.Action {
+	case RevisionAdd:
+		kind = ChangeAdded
+	case RevisionEdit, RevisionAuthor, RevisionRevert, RevisionTag, RevisionUntag:
+		kind = ChangeUpdated
+	case RevisionDelete:
+		kind = ChangeDeleted
+	case RevisionRestore:
+		kind = ChangeRestored
+	default:
+		return ChangeEvent{}, false
+	}
+	return ChangeEvent{
+		ID:      rev.ID,
+		Type:    kind,
+		Channel: rev.Channel,
+		QuoteID: rev.QuoteID,
+		Actor:   rev.Actor,
+		Action:  rev.Action,
+		Text:    rev.NewText,
+		Author:  rev.NewAuthor,
+		At:      rev.CreatedAt,
+	}, true
+}
+
+// changeSubscriberBuffer is the capacity of a subscription's channel.
+const changeSubscriberBuffer = 16
+
+// changeHub fans events out to subscribers. Publishing never blocks: each subscriber has its own
+// unbounded queue drained by a goroutine, so a slow reader delays only itself and never misses an
+// event. A nil hub publishes nothing.
+type changeHub struct {
+	mu     sync.Mutex
+	subs   map[*changeSubscriber]struct{}
+	done   chan struct{}
+	closed bool
+}
+
+type changeSubscriber struct {
+	channel string
+	mu      sync.Mutex
+	queue   []ChangeEvent
+	notify  chan struct{}
+}
+
+func newChangeHub() *changeHub {
+	return &changeHub{
+		subs: make(map[*changeSubscriber]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// subscribe returns a channel receiving the events published for channel, or for every channel
+// when it is empty. The channel is closed when ctx ends or the hub is closed.
+func (h *changeHub) subscribe(ctx context.Context, channel string) <-chan ChangeEvent {
+	out := make(chan ChangeEvent, changeSubscriberBuffer)
+	sub := &changeSubscriber{channel: channel, notify: make(chan struct{}, 1)}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(out)
+		return out
+	}
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer h.unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.done:
+				return
+			case <-sub.notify:
+			}
+			for _, ev := range sub.take() {
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				case <-h.done:
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (h *changeHub) unsubscribe(sub *changeSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// publish queues events for every subscriber whose channel they belong to.
+func (h *changeHub) publish(events ...ChangeEvent) {
+	if h == nil || len(events) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		sub.push(events)
+	}
+}
+
+// close ends every subscription. Later subscriptions are closed straight away.
+func (h *changeHub) close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+func (sub *changeSubscriber) push(events []ChangeEvent) {
+	sub.mu.Lock()
+	queued := false
+	for _, ev := range events {
+		if sub.channel == "" || ev.Channel == sub.channel {
+			sub.queue = append(sub.queue, ev)
+			queued = true
+		}
+	}
+	sub.mu.Unlock()
+	if queued {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (sub *changeSubscriber) take() []ChangeEvent {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	events := sub.queue
+	sub.queue = nil
+	return events
+}
+
+// Subscribe implements QuoteRepository. Events are published as each change commits.
+func (s *MemoryStore) Subscribe(ctx context.Context, channel string) (<-chan ChangeEvent, error) {
+	return s.events.subscribe(ctx, normalizeChannel(channel)), nil
+}
+
+// memChangeEvents returns the events reported by the revisions among records.
+func memChangeEvents(records []memRecord) []ChangeEvent {
+	var events []ChangeEvent
+	for _, rec := range records {
+		if rec.Op != memRecordRevision {
+			continue
+		}
+		if ev, ok := changeEvent(rec.Revision.Revision); ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// Subscribe implements QuoteRepository. The quote_revisions table doubles as the changelog: a
+// single goroutine, started by the first subscription, tails it and publishes every change
+// committed after that point. Changes made through this store wake it right away, and it polls
+// every ChangelogPoll for changes made by other processes sharing the database.
+func (s *QuoteStore) Subscribe(ctx context.Context, channel string) (<-chan ChangeEvent, error) {
+	if err := s.startTail(ctx); err != nil {
+		return nil, err
+	}
+	return s.events.subscribe(ctx, normalizeChannel(channel)), nil
+}
+
+// startTail starts tailing the changelog from its current end, unless it is already running.
+func (s *QuoteStore) startTail(ctx context.Context) error {
+	s.tailMu.Lock()
+	defer s.tailMu.Unlock()
+	if s.tailing {
+		return nil
+	}
+	var last int
+	if err := s.read.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM quote_revisions").Scan(&last); err != nil {
+		return fmt.Errorf("reading changelog position: %w", err)
+	}
+	s.tailing = true
+	go s.tailChanges(last)
+	return nil
+}
+
+// notifyChanged wakes the changelog tail after a write through this store.
+func (s *QuoteStore) notifyChanged() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// changelogBatch is how many changelog rows one query reads while catching up.
+const changelogBatch = 500
+
+func (s *QuoteStore) tailChanges(last int) {
+	var ticks <-chan time.Time
+	if s.options.ChangelogPoll > 0 {
+		ticker := time.NewTicker(s.options.ChangelogPoll)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-s.events.done:
+			return
+		case <-s.changed:
+		case <-ticks:
+		}
+		var err error
+		if last, err = s.readChanges(last); err != nil {
+			select {
+			case <-s.events.done:
+				return
+			default:
+				log.Printf("Error reading changelog: %v", err)
+			}
+		}
+	}
+}
+
+// readChanges publishes the changes recorded after revision last and returns the new position.
+func (s *QuoteStore) readChanges(last int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for {
+		revs, err := s.revisionsAfter(ctx, last)
+		if err != nil {
+			return last, err
+		}
+		if len(revs) == 0 {
+			// Restoring a backup can roll the history back below our position; follow it so
+			// changes made afterwards are not skipped.
+			var newest int
+			if err := s.read.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM quote_revisions").Scan(&newest); err != nil {
+				return last, fmt.Errorf("reading changelog position: %w", err)
+			}
+			return min(last, newest), nil
+		}
+		events := make([]ChangeEvent, 0, len(revs))
+		for _, rev := range revs {
+			if ev, ok := changeEvent(rev); ok {
+				events = append(events, ev)
+			}
+		}
+		s.events.publish(events...)
+		last = revs[len(revs)-1].ID
+		if len(revs) < changelogBatch {
+			return last, nil
+		}
+	}
+}
+
+func (s *QuoteStore) revisionsAfter(ctx context.Context, last int) ([]Revision, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT "+revisionColumns+" FROM quote_revisions WHERE id > ? ORDER BY id LIMIT ?", last, changelogBatch)
+	if err != nil {
+		return nil, fmt.Errorf("querying changelog: %w", err)
+	}
+	defer rows.Close()
+
+	var revs []Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning changelog: %w", err)
+		}
+		revs = append(revs, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating changelog: %w", err)
+	}
+	return revs, nil
+}
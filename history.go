@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Revision actions recorded in quote_revisions.
+const (
+	RevisionAdd     = "add"
+	RevisionEdit    = "edit"
+	RevisionAuthor  = "author"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+	RevisionPurge   = "purge"
+	RevisionApprove = "approve"
+)
+
+// Revision is one entry in a quote's append-only history. QuoteID is the quote's number within
+// Channel. Old and New hold the full text/author snapshot before and after the change so any
+// revision can be rolled back to.
+type Revision struct {
+	ID        int       `json:"id"`
+	QuoteID   int       `json:"quote_id"`
+	Channel   string    `json:"channel"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	OldText   string    `json:"old_text,omitempty"`
+	OldAuthor string    `json:"old_author,omitempty"`
+	NewText   string    `json:"new_text,omitempty"`
+	NewAuthor string    `json:"new_author,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// quoteRowID is the quote's internal primary key, only needed when recording.
+	quoteRowID int64
+}
+
+const revisionColumns = "id, channel_seq, channel, actor, action, old_text, old_author, new_text, new_author, note, created_at"
+
+// snapshotRevision returns a revision whose before and after snapshots both equal q.
+// Callers overwrite the fields the mutation actually changes.
+func snapshotRevision(q Quote, actor, action string) Revision {
+	return Revision{
+		QuoteID:    q.ID,
+		Channel:    q.Channel,
+		Actor:      actor,
+		Action:     action,
+		OldText:    q.Text,
+		OldAuthor:  q.Author,
+		NewText:    q.Text,
+		NewAuthor:  q.Author,
+		quoteRowID: q.RowID,
+	}
+}
+
+// insertRevisionQuery appends one revision; revisionArgs supplies its arguments.
+const insertRevisionQuery = `INSERT INTO quote_revisions(quote_id, channel, channel_seq, actor, action, old_text, old_author, new_text, new_author, note, created_at)
+                VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// revisionArgs returns the arguments of insertRevisionQuery for rev recorded at the given time.
+func revisionArgs(rev Revision, at time.Time) []any {
+	actor := strings.TrimSpace(rev.Actor)
+	if actor == "" {
+		actor = "unknown"
+	}
+	return []any{rev.quoteRowID, rev.Channel, rev.QuoteID, actor, rev.Action, rev.OldText, rev.OldAuthor, rev.NewText, rev.NewAuthor, rev.Note, formatSQLiteTime(at)}
+}
+
+// recordRevision appends rev to the history inside the mutation's transaction.
+func recordRevision(ctx context.Context, tx *sql.Tx, rev Revision) error {
+	if _, err := tx.ExecContext(ctx, insertRevisionQuery, revisionArgs(rev, time.Now())...); err != nil {
+		return fmt.Errorf("recording revision: %w", err)
+	}
+	return nil
+}
+
+func scanRevision(scanner rowScanner) (Revision, error) {
+	var rev Revision
+	var created string
+	if err := scanner.Scan(&rev.ID, &rev.QuoteID, &rev.Channel, &rev.Actor, &rev.Action, &rev.OldText, &rev.OldAuthor, &rev.NewText, &rev.NewAuthor, &rev.Note, &created); err != nil {
+		return Revision{}, err
+	}
+	parsed, err := parseSQLiteTime(created)
+	if err != nil {
+		return Revision{}, err
+	}
+	rev.CreatedAt = parsed
+	return rev, nil
+}
+
+// History returns up to limit revisions of a quote in channel, newest first. A limit of zero or
+// less returns all of them.
+func (s *QuoteStore) History(ctx context.Context, channel string, quoteID, limit int) ([]Revision, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.read.QueryContext(ctx, "SELECT "+revisionColumns+" FROM quote_revisions WHERE channel = ? AND channel_seq = ? ORDER BY id DESC LIMIT ?", normalizeChannel(channel), quoteID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating history: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, ErrNoQuotes
+	}
+	return revisions, nil
+}
+
+// Revert restores a live quote's text and author to the state recorded right after revisionID,
+// appending a new revert revision so the rollback itself is audited. Like Add, it returns a
+// *DuplicateQuoteError when another live quote in the channel has the text it would go back to.
+func (s *QuoteStore) Revert(ctx context.Context, channel string, quoteID, revisionID int, actor string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		target, err := scanRevision(tx.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM quote_revisions WHERE id = ? AND channel = ? AND channel_seq = ?", revisionID, normalizeChannel(channel), quoteID))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("no revision %d found for quote #%d", revisionID, quoteID)
+			}
+			return fmt.Errorf("fetching revision: %w", err)
+		}
+		old, err := liveQuoteTx(ctx, tx, channel, quoteID)
+		if err != nil {
+			return err
+		}
+		fingerprint := quoteFingerprint(target.NewText)
+		if err := duplicateOtherTx(ctx, tx, normalizeChannel(channel), fingerprint, old.RowID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET text = ?, author = ?, fingerprint = ? WHERE id = ?", target.NewText, target.NewAuthor, fingerprint, old.RowID); err != nil {
+			return fmt.Errorf("reverting quote: %w", err)
+		}
+		rev := snapshotRevision(old, actor, RevisionRevert)
+		rev.NewText = target.NewText
+		rev.NewAuthor = target.NewAuthor
+		rev.Note = fmt.Sprintf("reverted to revision %d", revisionID)
+		return recordRevision(ctx, tx, rev)
+	})
+}
+
+// describeRevision summarises a revision in a single short line suitable for chat, saying how long
+// before now it was made.
+func describeRevision(rev Revision, now time.Time) string {
+	var what string
+	switch rev.Action {
+	case RevisionAdd:
+		what = "added"
+	case RevisionEdit:
+		what = fmt.Sprintf("text edited (was \"%s\")", truncate(rev.OldText, 40))
+	case RevisionAuthor:
+		what = fmt.Sprintf("author %s -> %s", rev.OldAuthor, rev.NewAuthor)
+	case RevisionDelete:
+		what = "deleted"
+	case RevisionRestore:
+		what = "restored"
+	case RevisionRevert, RevisionApprove:
+		what = rev.Note
+	case RevisionPurge:
+		what = "purged"
+	default:
+		what = rev.Action
+	}
+	return fmt.Sprintf("r%d %s by %s %s", rev.ID, what, rev.Actor, relativeTime(rev.CreatedAt, now))
+}
+
+// formatRevisionDiff renders a revision as a multi-line diff of the fields it changed, dated using
+// display.
+func formatRevisionDiff(rev Revision, display TimeDisplay) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("revision %d  %s by %s at %s", rev.ID, rev.Action, rev.Actor, display.Format(rev.CreatedAt)))
+	if rev.Note != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", rev.Note))
+	}
+	sb.WriteString("\n")
+	writeField := func(name, oldValue, newValue string) {
+		if oldValue == newValue {
+			return
+		}
+		if oldValue != "" {
+			sb.WriteString(fmt.Sprintf("  - %s: %s\n", name, oldValue))
+		}
+		if newValue != "" {
+			sb.WriteString(fmt.Sprintf("  + %s: %s\n", name, newValue))
+		}
+	}
+	writeField("text", rev.OldText, rev.NewText)
+	writeField("author", rev.OldAuthor, rev.NewAuthor)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
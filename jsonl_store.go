@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// JSONLStore is a QuoteRepository backed by an append-only JSON-lines file. The file is a journal
+// of MemoryStore changes: it is replayed when the store is opened and every later change is
+// appended and synced before it takes effect, so existing lines are never rewritten.
+type JSONLStore struct {
+	*MemoryStore
+	file *os.File
+}
+
+// errStoreClosed is returned when writing to a store whose file has been closed.
+var errStoreClosed = errors.New("quote store is closed")
+
+// OpenJSONLStore opens (creating if needed) the journal at path and replays it into memory.
+// A final line cut short by a crash is dropped; any other malformed line is an error.
+func OpenJSONLStore(path string) (*JSONLStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	mem := NewMemoryStore()
+	if err := replayJournal(file, mem); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading journal %s: %w", path, err)
+	}
+
+	store := &JSONLStore{MemoryStore: mem, file: file}
+	mem.journal = store.append
+	return store, nil
+}
+
+// replayJournal applies every record in file to mem and truncates an incomplete trailing line.
+func replayJournal(file *os.File, mem *MemoryStore) error {
+	reader := bufio.NewReader(file)
+	var offset int64
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// The last write never finished; drop it so new records start on a clean line.
+				return file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec memRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		mem.applyLocked(rec)
+	}
+}
+
+// append writes records to the end of the journal in a single write and syncs the file.
+func (s *JSONLStore) append(records []memRecord) error {
+	if s.file == nil {
+		return errStoreClosed
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the journal file and ends every subscription. Reads keep working from memory;
+// further changes fail.
+func (s *JSONLStore) Close() error {
+	s.events.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListSort is a field ListPage can order quotes by.
+type ListSort string
+
+const (
+	ListSortID     ListSort = "id"
+	ListSortDate   ListSort = "date"
+	ListSortAuthor ListSort = "author"
+	ListSortRating ListSort = "rating"
+)
+
+// parseListSort validates a user-supplied sort field; an empty name sorts by ID.
+func parseListSort(name string) (ListSort, error) {
+	switch sort := ListSort(strings.ToLower(strings.TrimSpace(name))); sort {
+	case "":
+		return ListSortID, nil
+	case ListSortID, ListSortDate, ListSortAuthor, ListSortRating:
+		return sort, nil
+	case "score", "votes":
+		return ListSortRating, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q (use id, date, author or rating)", name)
+	}
+}
+
+// ListOptions selects one page of quotes. Ties in the sort order are broken by ID.
+type ListOptions struct {
+	Sort       ListSort
+	Descending bool
+	// Author keeps only quotes by this author (case-insensitive).
+	Author string
+	// SubmittedBy keeps only quotes added by this user (case-insensitive display name).
+	SubmittedBy string
+	// From and To keep only quotes added at or after From and before To; zero values are open ends.
+	From, To time.Time
+	// Limit is the page size; zero or less returns every remaining match.
+	Limit int
+	// Offset skips that many matches. It is ignored when After is set.
+	Offset int
+	// After continues from a previous page's NextCursor (keyset pagination).
+	After string
+}
+
+// ListPage is one page of quotes, together with the number of quotes matching the filters across
+// all pages and a cursor for the following page (empty on the last page).
+type ListPage struct {
+	Quotes     []Quote
+	Total      int
+	NextCursor string
+}
+
+// ErrInvalidCursor is returned when a cursor is malformed or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid list cursor")
+
+// listCursor is the decoded form of a keyset cursor: the sort key and ID of the last quote on the
+// previous page. Keys are backend-specific strings compared in the backend's own order.
+type listCursor struct {
+	Sort       ListSort `json:"s"`
+	Descending bool     `json:"d,omitempty"`
+	Key        string   `json:"k,omitempty"`
+	ID         int      `json:"id"`
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor parses opts.After, checking it belongs to the same sort order as opts.
+func decodeListCursor(opts ListOptions) (listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(opts.After)
+	if err != nil {
+		return listCursor{}, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return listCursor{}, ErrInvalidCursor
+	}
+	if c.Sort != opts.Sort || c.Descending != opts.Descending {
+		return listCursor{}, fmt.Errorf("%w: it was issued for a different sort order", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+// normalize fills in defaults and validates the sort.
+func (o ListOptions) normalize() (ListOptions, error) {
+	sort, err := parseListSort(string(o.Sort))
+	if err != nil {
+		return ListOptions{}, err
+	}
+	o.Sort = sort
+	o.Author = strings.TrimSpace(o.Author)
+	o.SubmittedBy = strings.TrimSpace(o.SubmittedBy)
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+	return o, nil
+}
+
+// listSortKeys maps each sort to the SQL expression ordering by it; ListSortID needs none beyond
+// the channel_seq tie-breaker.
+var listSortKeys = map[ListSort]string{
+	ListSortID:     "''",
+	ListSortDate:   "quotes.created_at",
+	ListSortAuthor: "quotes.author COLLATE NOCASE",
+	ListSortRating: "quotes.score",
+}
+
+// ListPage returns one page of live quotes in channel according to opts.
+func (s *QuoteStore) ListPage(ctx context.Context, channel string, opts ListOptions) (ListPage, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return ListPage{}, err
+	}
+
+	where := liveInChannel
+	args := []any{normalizeChannel(channel)}
+	if opts.Author != "" {
+		where += " AND quotes.author = ? COLLATE NOCASE"
+		args = append(args, opts.Author)
+	}
+	if opts.SubmittedBy != "" {
+		where += " AND quotes.submitted_by = ? COLLATE NOCASE"
+		args = append(args, opts.SubmittedBy)
+	}
+	if !opts.From.IsZero() {
+		where += " AND quotes.created_at >= ?"
+		args = append(args, formatSQLiteTime(opts.From))
+	}
+	if !opts.To.IsZero() {
+		where += " AND quotes.created_at < ?"
+		args = append(args, formatSQLiteTime(opts.To))
+	}
+
+	var page ListPage
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM quotes WHERE "+where, args...).Scan(&page.Total); err != nil {
+		return ListPage{}, fmt.Errorf("counting quotes: %w", err)
+	}
+
+	key := listSortKeys[opts.Sort]
+	dir, cmp := "ASC", ">"
+	if opts.Descending {
+		dir, cmp = "DESC", "<"
+	}
+	offset := opts.Offset
+	if opts.After != "" {
+		cursor, err := decodeListCursor(opts)
+		if err != nil {
+			return ListPage{}, err
+		}
+		where += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND quotes.channel_seq %[2]s ?))", key, cmp)
+		args = append(args, cursor.Key, cursor.Key, cursor.ID)
+		offset = 0
+	}
+	// One extra row tells us whether there is a next page.
+	limit := -1
+	if opts.Limit > 0 {
+		limit = opts.Limit + 1
+	}
+	query := fmt.Sprintf("SELECT %s, %s FROM quotes WHERE %s ORDER BY %s %s, quotes.channel_seq %s LIMIT ? OFFSET ?", quoteColumns, key, where, key, dir, dir)
+	rows, err := s.read.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return ListPage{}, fmt.Errorf("listing quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var sortKey string
+		q, err := scanQuote(trailingScanner{rows: rows, extra: []any{&sortKey}})
+		if err != nil {
+			return ListPage{}, fmt.Errorf("scanning quote: %w", err)
+		}
+		page.Quotes = append(page.Quotes, q)
+		keys = append(keys, sortKey)
+	}
+	if err := rows.Err(); err != nil {
+		return ListPage{}, fmt.Errorf("iterating quotes: %w", err)
+	}
+
+	if opts.Limit > 0 && len(page.Quotes) > opts.Limit {
+		page.Quotes = page.Quotes[:opts.Limit]
+		last := page.Quotes[opts.Limit-1]
+		page.NextCursor = listCursor{Sort: opts.Sort, Descending: opts.Descending, Key: keys[opts.Limit-1], ID: last.ID}.encode()
+	}
+	return page, nil
+}
+
+// trailingScanner lets scanQuote read the quote columns of a row whose remaining columns are
+// scanned into extra.
+type trailingScanner struct {
+	rows  rowScanner
+	extra []any
+}
+
+func (s trailingScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.extra...)...)
+}
@@ -19,20 +19,22 @@ import (
 func main() {
 	var (
 		dbPath        string
+		storeKind     string
 		twitchUser    string
 		twitchOAuth   string
 		twitchChannel string
 		mode          string
 	)
-	flag.StringVar(&dbPath, "db", "quotes.db", "Path to SQLite database file")
+	flag.StringVar(&dbPath, "db", "quotes.db", "Path to the database file, optionally prefixed with a store scheme (sqlite:, jsonl:, memory:)")
+	flag.StringVar(&storeKind, "store", "", "Storage backend: sqlite (default), memory or jsonl")
 	flag.StringVar(&twitchUser, "user", "", "Twitch bot username")
 	flag.StringVar(&twitchOAuth, "oauth", "", "Twitch OAuth token (format: oauth:xxxx)")
-	flag.StringVar(&twitchChannel, "channel", "", "Twitch channel to join")
+	flag.StringVar(&twitchChannel, "channel", "", "Twitch channel to join (comma-separated for several)")
 	flag.StringVar(&mode, "mode", "twitch", "Mode: twitch or cli")
 	flag.Parse()
 	applyEnvDefaults(&mode, &dbPath, &twitchUser, &twitchOAuth, &twitchChannel)
 
-	config, err := setup(mode, dbPath, twitchUser, twitchOAuth, twitchChannel)
+	config, err := setup(mode, dbPath, storeKind, twitchUser, twitchOAuth, twitchChannel)
 	if err != nil {
 		log.Fatalf("Error during setup: %v", err)
 	}
@@ -40,28 +42,60 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	store, err := NewQuoteStore(ctx, config.DBPath)
+	store, err := openRepository(ctx, config.Store, config.DBPath, config.SQLiteSettings())
 	if err != nil {
 		log.Fatalf("Error initializing database: %v", err)
 	}
 	defer store.Close()
 
-	handler := NewCommandHandler(store)
+	if adopted, err := store.AdoptUnscopedQuotes(ctx, config.PrimaryChannel()); err != nil {
+		log.Fatalf("Error assigning existing quotes to #%s: %v", config.PrimaryChannel(), err)
+	} else if adopted > 0 {
+		log.Printf("Assigned %d existing quote%s to #%s", adopted, pluralSuffix(int(adopted)), config.PrimaryChannel())
+	}
+
+	go runTrashPurger(ctx, store, config.TrashRetentionDays)
+	if sqlite, ok := store.(*QuoteStore); ok {
+		go runBackupScheduler(ctx, sqlite, config.BackupSettings())
+	}
+
+	randomSettings, err := config.RandomSettings()
+	if err != nil {
+		log.Fatalf("Error in configuration: %v", err)
+	}
+	channelSettings, err := config.ChannelSettings()
+	if err != nil {
+		log.Fatalf("Error in configuration: %v", err)
+	}
+	handler := NewCommandHandler(store, newHelixStreamInfo(config.TwitchClientID, config.TwitchOAuth), randomSettings, channelSettings)
 
 	switch strings.ToLower(config.Mode) {
 	case "cli":
-		runCLI(ctx, store, handler)
+		runCLI(ctx, store, handler, config)
 	case "tui":
-		if err := runTUI(ctx, config); err != nil && !errors.Is(err, context.Canceled) {
+		if err := runTUI(ctx, config, store, channelSettings.Display()); err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("Error running TUI: %v", err)
 		}
 	case "twitch":
-		if err := validateTwitchConfig(config.TwitchUser, config.TwitchOAuth, config.TwitchChannel); err != nil {
+		if err := validateTwitchConfig(config.TwitchUser, config.TwitchOAuth, config.PrimaryChannel()); err != nil {
 			log.Fatal(err)
 		}
+		// Cooldowns only apply to chat, so only the bot keeps the cooldown file; a CLI or TUI
+		// started next to it would otherwise overwrite the bot's cooldowns with its own.
+		if path := config.CooldownFile; path != "" {
+			if err := handler.LoadCooldowns(path); err != nil {
+				log.Printf("Error loading cooldowns from %s: %v", path, err)
+			}
+			go runCooldownSaver(ctx, handler, path)
+			defer func() {
+				if err := handler.SaveCooldowns(path); err != nil {
+					log.Printf("Error saving cooldowns to %s: %v", path, err)
+				}
+			}()
+		}
 		client := configureTwitchClient(config.TwitchUser, config.TwitchOAuth)
-		bot := NewTwitchBot(client, handler, config.TwitchChannel)
-		log.Printf("Connecting to Twitch channel #%s as %s...", config.TwitchChannel, config.TwitchUser)
+		bot := NewTwitchBot(client, handler, config.Channels())
+		log.Printf("Connecting to Twitch channel(s) #%s as %s...", strings.Join(config.Channels(), ", #"), config.TwitchUser)
 		if err := bot.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("Error running Twitch bot: %v", err)
 		}
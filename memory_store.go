@@ -0,0 +1,1243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a QuoteRepository that keeps everything in memory. It supports every feature of
+// the SQLite store and is meant for tests and ephemeral demos; JSONLStore builds on it to persist
+// changes to a file.
+//
+// Every change is expressed as a list of memRecords that are first handed to the journal (when
+// one is set) and then applied, so replaying a journal rebuilds exactly the same state.
+type MemoryStore struct {
+	mu             sync.Mutex
+	quotes         map[int64]*memQuote
+	counters       map[string]int
+	revisions      []storedRevision
+	lastRowID      int64
+	lastRevisionID int
+	pending        map[int]*PendingQuote
+	lastPendingID  int
+	random         *rand.Rand
+	// adopted is set once AdoptUnscopedQuotes has run, like the SQLite store's flag.
+	adopted bool
+	events  *changeHub
+
+	// journal, when set, receives every change before it is applied; an error aborts the change.
+	journal func(records []memRecord) error
+}
+
+// memQuote is a quote together with the state the SQLite store keeps in other columns and tables.
+type memQuote struct {
+	Quote
+	Tags  []string `json:"tags,omitempty"`
+	Drawn bool     `json:"drawn,omitempty"`
+	// Votes maps each voter to their +1 or -1.
+	Votes map[string]int `json:"votes,omitempty"`
+}
+
+// storedRevision is a revision together with the internal ID of the quote it belongs to.
+type storedRevision struct {
+	Revision
+	QuoteRowID int64 `json:"quote_row_id"`
+}
+
+// memRecord is a single change to a MemoryStore.
+type memRecord struct {
+	Op       string          `json:"op"`
+	Quote    *memQuote       `json:"quote,omitempty"`
+	Revision *storedRevision `json:"revision,omitempty"`
+	Pending  *PendingQuote   `json:"pending,omitempty"`
+	RowIDs   []int64         `json:"row_ids,omitempty"`
+	Channel  string          `json:"channel,omitempty"`
+	Seq      int             `json:"seq,omitempty"`
+	At       *time.Time      `json:"at,omitempty"`
+}
+
+// memRecord operations.
+const (
+	// memRecordQuote inserts or replaces a quote.
+	memRecordQuote = "quote"
+	// memRecordRevision appends a revision to the history.
+	memRecordRevision = "revision"
+	// memRecordPurge removes the quotes listed in RowIDs for good.
+	memRecordPurge = "purge"
+	// memRecordAdopt moves unscoped quotes and their history into Channel, renumbered by Seq.
+	memRecordAdopt = "adopt"
+	// memRecordPending inserts or replaces a submission in the approval queue.
+	memRecordPending = "pending"
+	// memRecordRefill puts the quotes listed in RowIDs back into the shuffle bag.
+	memRecordRefill = "refill"
+	// memRecordDraw takes the quote listed in RowIDs out of the shuffle bag, shown At.
+	memRecordDraw = "draw"
+	// memRecordView counts a view of the quote listed in RowIDs, shown At.
+	memRecordView = "view"
+)
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		quotes:   make(map[int64]*memQuote),
+		counters: make(map[string]int),
+		pending:  make(map[int]*PendingQuote),
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		events:   newChangeHub(),
+	}
+}
+
+// Close implements QuoteRepository by ending every subscription.
+func (s *MemoryStore) Close() error {
+	s.events.close()
+	return nil
+}
+
+// commitLocked journals records, applies them and publishes the changes they make. The caller
+// must hold s.mu.
+func (s *MemoryStore) commitLocked(records ...memRecord) error {
+	if s.journal != nil {
+		if err := s.journal(records); err != nil {
+			return fmt.Errorf("writing journal: %w", err)
+		}
+	}
+	for _, rec := range records {
+		s.applyLocked(rec)
+	}
+	s.events.publish(memChangeEvents(records)...)
+	return nil
+}
+
+// applyLocked applies a single record. The caller must hold s.mu.
+func (s *MemoryStore) applyLocked(rec memRecord) {
+	switch rec.Op {
+	case memRecordQuote:
+		q := rec.Quote.clone()
+		s.quotes[q.RowID] = &q
+		if q.ID > s.counters[q.Channel] {
+			s.counters[q.Channel] = q.ID
+		}
+		if q.RowID > s.lastRowID {
+			s.lastRowID = q.RowID
+		}
+	case memRecordRevision:
+		s.revisions = append(s.revisions, *rec.Revision)
+		if rec.Revision.ID > s.lastRevisionID {
+			s.lastRevisionID = rec.Revision.ID
+		}
+	case memRecordPurge:
+		for _, rowID := range rec.RowIDs {
+			delete(s.quotes, rowID)
+		}
+	case memRecordAdopt:
+		for rowID, q := range s.quotes {
+			if q.Channel == "" {
+				adopted := q.clone()
+				adopted.Channel = rec.Channel
+				adopted.ID += rec.Seq
+				s.quotes[rowID] = &adopted
+			}
+		}
+		for i := range s.revisions {
+			if s.revisions[i].Channel == "" {
+				s.revisions[i].Channel = rec.Channel
+				s.revisions[i].QuoteID += rec.Seq
+			}
+		}
+		if last := s.counters[""] + rec.Seq; last > s.counters[rec.Channel] {
+			s.counters[rec.Channel] = last
+		}
+		delete(s.counters, "")
+		s.adopted = true
+	case memRecordRefill, memRecordDraw, memRecordView:
+		for _, rowID := range rec.RowIDs {
+			q, ok := s.quotes[rowID]
+			if !ok {
+				continue
+			}
+			shown := q.clone()
+			switch rec.Op {
+			case memRecordRefill:
+				shown.Drawn = false
+			case memRecordDraw:
+				shown.Drawn = true
+				shown.LastShownAt = *rec.At
+			case memRecordView:
+				shown.Views++
+				shown.LastShownAt = *rec.At
+			}
+			s.quotes[rowID] = &shown
+		}
+	case memRecordPending:
+		p := *rec.Pending
+		p.Tags = append([]string(nil), rec.Pending.Tags...)
+		s.pending[p.ID] = &p
+		if p.ID > s.lastPendingID {
+			s.lastPendingID = p.ID
+		}
+	}
+}
+
+func (q *memQuote) clone() memQuote {
+	c := *q
+	c.Tags = append([]string(nil), q.Tags...)
+	if q.Votes != nil {
+		c.Votes = make(map[string]int, len(q.Votes))
+		for voter, value := range q.Votes {
+			c.Votes[voter] = value
+		}
+	}
+	return c
+}
+
+func quoteRecord(q memQuote) memRecord {
+	return memRecord{Op: memRecordQuote, Quote: &q}
+}
+
+// shownRecord records a draw or view of the quote with row ID rowID at now. Unlike a quote record
+// it does not repeat the quote, so showing quotes keeps the journal small.
+func shownRecord(op string, rowID int64, now time.Time) memRecord {
+	return memRecord{Op: op, RowIDs: []int64{rowID}, At: &now}
+}
+
+// revisionRecordsLocked numbers revs after the last recorded revision and wraps them as records.
+// The caller must hold s.mu.
+func (s *MemoryStore) revisionRecordsLocked(revs ...Revision) []memRecord {
+	records := make([]memRecord, len(revs))
+	now := time.Now().UTC()
+	for i, rev := range revs {
+		rev.ID = s.lastRevisionID + i + 1
+		rev.Actor = strings.TrimSpace(rev.Actor)
+		if rev.Actor == "" {
+			rev.Actor = "unknown"
+		}
+		rev.CreatedAt = now
+		records[i] = memRecord{Op: memRecordRevision, Revision: &storedRevision{Revision: rev, QuoteRowID: rev.quoteRowID}}
+	}
+	return records
+}
+
+// findLocked returns the quote numbered id in channel, either live or trashed depending on deleted.
+// The caller must hold s.mu.
+func (s *MemoryStore) findLocked(channel string, id int, deleted bool) *memQuote {
+	channel = normalizeChannel(channel)
+	for _, q := range s.quotes {
+		if q.Channel == channel && q.ID == id && q.DeletedAt.IsZero() != deleted {
+			return q
+		}
+	}
+	return nil
+}
+
+// liveLocked returns the live quotes in channel ordered by ID. The caller must hold s.mu.
+func (s *MemoryStore) liveLocked(channel string) []*memQuote {
+	channel = normalizeChannel(channel)
+	var live []*memQuote
+	for _, q := range s.quotes {
+		if q.Channel == channel && q.DeletedAt.IsZero() {
+			live = append(live, q)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+	return live
+}
+
+// duplicateLocked returns a *DuplicateQuoteError naming the lowest-numbered live quote in channel,
+// other than the one with row ID except, whose text has fingerprint. The caller must hold s.mu.
+func (s *MemoryStore) duplicateLocked(channel, fingerprint string, except int64) error {
+	if fingerprint == "" {
+		return nil
+	}
+	for _, q := range s.liveLocked(channel) {
+		if q.RowID != except && quoteFingerprint(q.Text) == fingerprint {
+			return &DuplicateQuoteError{ExistingID: q.ID}
+		}
+	}
+	return nil
+}
+
+func quoteValues(quotes []*memQuote) []Quote {
+	values := make([]Quote, len(quotes))
+	for i, q := range quotes {
+		values[i] = q.Quote
+	}
+	return values
+}
+
+// Add implements QuoteRepository.
+func (s *MemoryStore) Add(_ context.Context, channel string, nq NewQuote) (AddResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, q, rev, err := s.prepareAddLocked(channel, nq)
+	if err != nil {
+		return AddResult{}, err
+	}
+	if err := s.commitLocked(append([]memRecord{quoteRecord(q)}, s.revisionRecordsLocked(rev)...)...); err != nil {
+		return AddResult{}, err
+	}
+	return result, nil
+}
+
+// prepareAddLocked checks nq against channel's live quotes and builds the quote and the add
+// revision that adding it records, without committing them. The caller must hold s.mu.
+func (s *MemoryStore) prepareAddLocked(channel string, nq NewQuote) (AddResult, memQuote, Revision, error) {
+	text, author, tags, err := validateNewQuote(nq)
+	if err != nil {
+		return AddResult{}, memQuote{}, Revision{}, err
+	}
+	channel = normalizeChannel(channel)
+	fingerprint := quoteFingerprint(text)
+
+	live := s.liveLocked(channel)
+	if fingerprint != "" {
+		for _, q := range live {
+			if quoteFingerprint(q.Text) == fingerprint {
+				return AddResult{}, memQuote{}, Revision{}, &DuplicateQuoteError{ExistingID: q.ID}
+			}
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].RowID > live[j].RowID })
+	if len(live) > recentQuoteWindow {
+		live = live[:recentQuoteWindow]
+	}
+	result := AddResult{Similar: closestQuote(fingerprint, quoteValues(live))}
+	q, rev := s.newQuoteLocked(channel, text, author, tags, nq)
+	result.ID = q.ID
+	return result, q, rev, nil
+}
+
+// newQuoteLocked numbers a validated new quote after the last one in channel and builds it along
+// with its add revision. The caller must hold s.mu.
+func (s *MemoryStore) newQuoteLocked(channel, text, author string, tags []string, nq NewQuote) (memQuote, Revision) {
+	q := memQuote{
+		Quote: Quote{
+			ID:          s.counters[channel] + 1,
+			RowID:       s.lastRowID + 1,
+			Channel:     channel,
+			Text:        text,
+			Author:      author,
+			Game:        strings.TrimSpace(nq.Game),
+			StreamTitle: strings.TrimSpace(nq.StreamTitle),
+			SubmittedBy: strings.TrimSpace(nq.Actor),
+			SubmitterID: strings.TrimSpace(nq.ActorID),
+			Platform:    strings.TrimSpace(nq.Platform),
+			CreatedAt:   nq.createdAt().UTC(),
+		},
+		Tags: tags,
+	}
+	rev := Revision{
+		QuoteID:    q.ID,
+		Channel:    channel,
+		quoteRowID: q.RowID,
+		Actor:      nq.Actor,
+		Action:     RevisionAdd,
+		NewText:    text,
+		NewAuthor:  author,
+		Note:       formatTags(tags),
+	}
+	return q, rev
+}
+
+// stageLocked returns a copy of the store whose changes are collected in the returned slice
+// instead of being journaled, so a batch can be checked row by row against its own earlier rows
+// and then committed to s in one go. The caller must hold s.mu.
+func (s *MemoryStore) stageLocked() (*MemoryStore, *[]memRecord) {
+	stage := &MemoryStore{
+		quotes:         make(map[int64]*memQuote, len(s.quotes)),
+		counters:       make(map[string]int, len(s.counters)),
+		revisions:      append([]storedRevision(nil), s.revisions...),
+		lastRowID:      s.lastRowID,
+		lastRevisionID: s.lastRevisionID,
+		pending:        s.pending,
+		lastPendingID:  s.lastPendingID,
+	}
+	for rowID, q := range s.quotes {
+		c := q.clone()
+		stage.quotes[rowID] = &c
+	}
+	for channel, seq := range s.counters {
+		stage.counters[channel] = seq
+	}
+	var records []memRecord
+	stage.journal = func(batch []memRecord) error {
+		records = append(records, batch...)
+		return nil
+	}
+	return stage, &records
+}
+
+// AddMany implements QuoteRepository.
+func (s *MemoryStore) AddMany(_ context.Context, channel string, quotes []NewQuote, opts BulkOptions) ([]int, error) {
+	quotes, err := validateAddMany(quotes, opts)
+	if err != nil {
+		return nil, err
+	}
+	channel = normalizeChannel(channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The batch was checked against itself, so only the quotes already live can be duplicates.
+	existing := make(map[string]int)
+	for _, q := range s.liveLocked(channel) {
+		if fingerprint := quoteFingerprint(q.Text); fingerprint != "" && existing[fingerprint] == 0 {
+			existing[fingerprint] = q.ID
+		}
+	}
+	stage, records := s.stageLocked()
+	ids := make([]int, len(quotes))
+	for i, nq := range quotes {
+		text, author, tags, _ := validateNewQuote(nq)
+		if id, ok := existing[quoteFingerprint(text)]; ok {
+			return nil, rowFailed(i, &DuplicateQuoteError{ExistingID: id})
+		}
+		q, rev := stage.newQuoteLocked(channel, text, author, tags, nq)
+		if err := stage.commitLocked(append([]memRecord{quoteRecord(q)}, stage.revisionRecordsLocked(rev)...)...); err != nil {
+			return nil, rowFailed(i, err)
+		}
+		ids[i] = q.ID
+		opts.progress(i+1, len(quotes))
+	}
+	if err := s.commitLocked(*records...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UpdateMany implements QuoteRepository.
+func (s *MemoryStore) UpdateMany(_ context.Context, channel string, updates []QuoteUpdate, opts BulkOptions) error {
+	updates, err := validateUpdateMany(updates)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stage, records := s.stageLocked()
+	for i, u := range updates {
+		old := stage.findLocked(channel, u.ID, false)
+		if old == nil {
+			return rowFailed(i, fmt.Errorf("no quote with id %d found", u.ID))
+		}
+		revs := updateRevisions(old.Quote, u, opts.Actor)
+		updated := old.clone()
+		updated.Text = revs[len(revs)-1].NewText
+		updated.Author = revs[len(revs)-1].NewAuthor
+		if err := stage.commitLocked(append([]memRecord{quoteRecord(updated)}, stage.revisionRecordsLocked(revs...)...)...); err != nil {
+			return rowFailed(i, err)
+		}
+		opts.progress(i+1, len(updates))
+	}
+	return s.commitLocked(*records...)
+}
+
+// DeleteMany implements QuoteRepository.
+func (s *MemoryStore) DeleteMany(_ context.Context, channel string, ids []int, opts BulkOptions) error {
+	if err := validateDeleteMany(ids); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stage, records := s.stageLocked()
+	for i, id := range ids {
+		err := stage.mutateLocked(channel, id, opts.Actor, RevisionDelete, func(q *memQuote, _ *Revision) {
+			q.DeletedAt = time.Now().UTC()
+			q.DeletedBy = opts.Actor
+		})
+		if err != nil {
+			return rowFailed(i, err)
+		}
+		opts.progress(i+1, len(ids))
+	}
+	return s.commitLocked(*records...)
+}
+
+// Random implements QuoteRepository with the same shuffle-bag semantics as QuoteStore.Random.
+func (s *MemoryStore) Random(_ context.Context, channel string, opts RandomOptions) (*Quote, error) {
+	var tag string
+	if opts.Tag != "" {
+		var ok bool
+		if tag, ok = normalizeTag(opts.Tag); !ok {
+			return nil, fmt.Errorf("invalid tag %q", opts.Tag)
+		}
+	}
+	game := strings.TrimSpace(opts.Game)
+	author := strings.TrimSpace(opts.Author)
+	submitter := strings.TrimSpace(opts.SubmittedBy)
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pool []*memQuote
+	for _, q := range s.liveLocked(channel) {
+		if tag != "" && !containsString(q.Tags, tag) {
+			continue
+		}
+		if game != "" && !strings.EqualFold(q.Game, game) {
+			continue
+		}
+		if author != "" && !strings.EqualFold(q.Author, author) {
+			continue
+		}
+		if submitter != "" && !strings.EqualFold(q.SubmittedBy, submitter) {
+			continue
+		}
+		pool = append(pool, q)
+	}
+	if len(pool) == 0 {
+		return nil, ErrNoQuotes
+	}
+
+	pick := func(keep func(q *memQuote) bool) []randomCandidate {
+		var candidates []randomCandidate
+		for _, q := range pool {
+			if keep(q) {
+				candidates = append(candidates, randomCandidate{rowID: q.RowID, created: q.CreatedAt, score: q.Score})
+			}
+		}
+		return candidates
+	}
+	stale := func(q *memQuote) bool {
+		return q.LastShownAt.IsZero() || q.LastShownAt.Before(now.Add(-opts.FreshWithin))
+	}
+
+	var candidates []randomCandidate
+	if opts.FreshWithin > 0 {
+		candidates = pick(func(q *memQuote) bool { return stale(q) && !q.Drawn })
+		if len(candidates) == 0 {
+			candidates = pick(stale)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = pick(func(q *memQuote) bool { return !q.Drawn })
+	}
+	var records []memRecord
+	if len(candidates) == 0 {
+		// Every matching quote has been drawn: start a new cycle for them.
+		refill := memRecord{Op: memRecordRefill}
+		for _, q := range pool {
+			refill.RowIDs = append(refill.RowIDs, q.RowID)
+		}
+		records = append(records, refill)
+		candidates = pick(func(*memQuote) bool { return true })
+	}
+
+	rowID := pickWeighted(candidates, opts.Weight, now, s.random.Float64())
+	if err := s.commitLocked(append(records, shownRecord(memRecordDraw, rowID, now))...); err != nil {
+		return nil, err
+	}
+	shown := s.quotes[rowID].Quote
+	return &shown, nil
+}
+
+// Search implements QuoteRepository by evaluating the parsed query against every live quote.
+func (s *MemoryStore) Search(_ context.Context, channel string, query string, limit int) ([]SearchResult, int, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, ErrNoQuotes
+	}
+	node, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.Lock()
+	var results []SearchResult
+	for _, q := range s.liveLocked(channel) {
+		doc := newSearchDoc(q.Quote)
+		if node.matches(doc) {
+			results = append(results, SearchResult{Quote: q.Quote, Rank: rankMatch(node, doc), Snippet: snippetMatch(node, q.Text)})
+		}
+	}
+	s.mu.Unlock()
+
+	if len(results) == 0 {
+		return nil, 0, ErrNoQuotes
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+	total := len(results)
+	if limit > 0 && limit < total {
+		results = results[:limit]
+	}
+	return results, total, nil
+}
+
+// List implements QuoteRepository.
+func (s *MemoryStore) List(_ context.Context, channel string) ([]Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live := s.liveLocked(channel)
+	if len(live) == 0 {
+		return nil, ErrNoQuotes
+	}
+	return quoteValues(live), nil
+}
+
+// memListKey is the sort key MemoryStore cursors carry for q: UTC timestamps with a fixed width
+// compare the same as strings as they do as times, and authors compare case-insensitively.
+func memListKey(sort ListSort, q Quote) string {
+	switch sort {
+	case ListSortDate:
+		return q.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000000Z")
+	case ListSortAuthor:
+		return strings.ToLower(q.Author)
+	case ListSortRating:
+		// Offset scores so negative ones also sort correctly as zero-padded strings.
+		return fmt.Sprintf("%020d", int64(q.Score)+1<<40)
+	default:
+		return ""
+	}
+}
+
+// ListPage implements QuoteRepository with the same ordering and cursor semantics as QuoteStore.
+func (s *MemoryStore) ListPage(_ context.Context, channel string, opts ListOptions) (ListPage, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return ListPage{}, err
+	}
+	var cursor *listCursor
+	if opts.After != "" {
+		c, err := decodeListCursor(opts)
+		if err != nil {
+			return ListPage{}, err
+		}
+		cursor = &c
+	}
+
+	type entry struct {
+		quote Quote
+		key   string
+	}
+	s.mu.Lock()
+	var matches []entry
+	for _, q := range s.liveLocked(channel) {
+		if opts.Author != "" && !strings.EqualFold(q.Author, opts.Author) {
+			continue
+		}
+		if opts.SubmittedBy != "" && !strings.EqualFold(q.SubmittedBy, opts.SubmittedBy) {
+			continue
+		}
+		if !opts.From.IsZero() && q.CreatedAt.Before(opts.From) {
+			continue
+		}
+		if !opts.To.IsZero() && !q.CreatedAt.Before(opts.To) {
+			continue
+		}
+		matches = append(matches, entry{quote: q.Quote, key: memListKey(opts.Sort, q.Quote)})
+	}
+	s.mu.Unlock()
+
+	// before reports whether a sorts ahead of b in the requested direction.
+	before := func(aKey string, aID int, bKey string, bID int) bool {
+		if aKey != bKey {
+			return (aKey < bKey) != opts.Descending
+		}
+		return (aID < bID) != opts.Descending
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return before(matches[i].key, matches[i].quote.ID, matches[j].key, matches[j].quote.ID)
+	})
+
+	page := ListPage{Total: len(matches)}
+	rest := matches
+	if cursor != nil {
+		start := sort.Search(len(matches), func(i int) bool {
+			return before(cursor.Key, cursor.ID, matches[i].key, matches[i].quote.ID)
+		})
+		rest = matches[start:]
+	} else if opts.Offset < len(matches) {
+		rest = matches[opts.Offset:]
+	} else {
+		rest = nil
+	}
+	if opts.Limit > 0 && len(rest) > opts.Limit {
+		last := rest[opts.Limit-1]
+		page.NextCursor = listCursor{Sort: opts.Sort, Descending: opts.Descending, Key: last.key, ID: last.quote.ID}.encode()
+		rest = rest[:opts.Limit]
+	}
+	for _, e := range rest {
+		page.Quotes = append(page.Quotes, e.quote)
+	}
+	return page, nil
+}
+
+// GetByID implements QuoteRepository.
+func (s *MemoryStore) GetByID(_ context.Context, channel string, id int) (*Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		return nil, ErrNoQuotes
+	}
+	found := q.Quote
+	return &found, nil
+}
+
+// Latest implements QuoteRepository.
+func (s *MemoryStore) Latest(_ context.Context, channel string) (*Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live := s.liveLocked(channel)
+	if len(live) == 0 {
+		return nil, ErrNoQuotes
+	}
+	latest := live[len(live)-1].Quote
+	return &latest, nil
+}
+
+// Count implements QuoteRepository.
+func (s *MemoryStore) Count(_ context.Context, channel string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.liveLocked(channel)), nil
+}
+
+// mutateLocked loads the live quote numbered id, lets change modify a copy of it and the revision
+// describing the change, and commits both. The caller must hold s.mu.
+func (s *MemoryStore) mutateLocked(channel string, id int, actor, action string, change func(q *memQuote, rev *Revision)) error {
+	old := s.findLocked(channel, id, false)
+	if old == nil {
+		return fmt.Errorf("no quote with id %d found", id)
+	}
+	updated := old.clone()
+	rev := snapshotRevision(old.Quote, actor, action)
+	change(&updated, &rev)
+	return s.commitLocked(append([]memRecord{quoteRecord(updated)}, s.revisionRecordsLocked(rev)...)...)
+}
+
+// UpdateText implements QuoteRepository.
+func (s *MemoryStore) UpdateText(_ context.Context, channel string, id int, newText, actor string) error {
+	newText = strings.TrimSpace(newText)
+	if newText == "" {
+		return fmt.Errorf("quote text cannot be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mutateLocked(channel, id, actor, RevisionEdit, func(q *memQuote, rev *Revision) {
+		q.Text = newText
+		rev.NewText = newText
+	})
+}
+
+// UpdateAuthor implements QuoteRepository.
+func (s *MemoryStore) UpdateAuthor(_ context.Context, channel string, id int, newAuthor, actor string) error {
+	newAuthor = strings.TrimSpace(newAuthor)
+	if newAuthor == "" {
+		return fmt.Errorf("author cannot be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mutateLocked(channel, id, actor, RevisionAuthor, func(q *memQuote, rev *Revision) {
+		q.Author = newAuthor
+		rev.NewAuthor = newAuthor
+	})
+}
+
+// Delete implements QuoteRepository.
+func (s *MemoryStore) Delete(_ context.Context, channel string, id int, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mutateLocked(channel, id, actor, RevisionDelete, func(q *memQuote, _ *Revision) {
+		q.DeletedAt = time.Now().UTC()
+		q.DeletedBy = actor
+	})
+}
+
+// Restore implements QuoteRepository.
+func (s *MemoryStore) Restore(_ context.Context, channel string, id int, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.findLocked(channel, id, true)
+	if old == nil {
+		return fmt.Errorf("no deleted quote with id %d found", id)
+	}
+	if err := s.duplicateLocked(channel, quoteFingerprint(old.Text), old.RowID); err != nil {
+		return err
+	}
+	restored := old.clone()
+	restored.DeletedAt = time.Time{}
+	restored.DeletedBy = ""
+	rev := snapshotRevision(restored.Quote, actor, RevisionRestore)
+	return s.commitLocked(append([]memRecord{quoteRecord(restored)}, s.revisionRecordsLocked(rev)...)...)
+}
+
+// Trash implements QuoteRepository.
+func (s *MemoryStore) Trash(_ context.Context, channel string, limit int) ([]Quote, error) {
+	channel = normalizeChannel(channel)
+	s.mu.Lock()
+	var trashed []Quote
+	for _, q := range s.quotes {
+		if q.Channel == channel && !q.DeletedAt.IsZero() {
+			trashed = append(trashed, q.Quote)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(trashed) == 0 {
+		return nil, ErrNoQuotes
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		if !trashed[i].DeletedAt.Equal(trashed[j].DeletedAt) {
+			return trashed[i].DeletedAt.After(trashed[j].DeletedAt)
+		}
+		return trashed[i].RowID > trashed[j].RowID
+	})
+	if limit > 0 && limit < len(trashed) {
+		trashed = trashed[:limit]
+	}
+	return trashed, nil
+}
+
+// GetDeleted implements QuoteRepository.
+func (s *MemoryStore) GetDeleted(_ context.Context, channel string, id int) (*Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, true)
+	if q == nil {
+		return nil, ErrNoQuotes
+	}
+	found := q.Quote
+	return &found, nil
+}
+
+// PurgeTrash implements QuoteRepository.
+func (s *MemoryStore) PurgeTrash(_ context.Context, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rowIDs []int64
+	var revs []Revision
+	for _, q := range s.quotes {
+		if !q.DeletedAt.IsZero() && q.DeletedAt.Before(cutoff) {
+			rowIDs = append(rowIDs, q.RowID)
+			revs = append(revs, snapshotRevision(q.Quote, "system", RevisionPurge))
+		}
+	}
+	if len(rowIDs) == 0 {
+		return 0, nil
+	}
+	sort.Slice(rowIDs, func(i, j int) bool { return rowIDs[i] < rowIDs[j] })
+	sort.Slice(revs, func(i, j int) bool { return revs[i].quoteRowID < revs[j].quoteRowID })
+	records := append(s.revisionRecordsLocked(revs...), memRecord{Op: memRecordPurge, RowIDs: rowIDs})
+	if err := s.commitLocked(records...); err != nil {
+		return 0, err
+	}
+	return int64(len(rowIDs)), nil
+}
+
+// AddTags implements QuoteRepository.
+func (s *MemoryStore) AddTags(_ context.Context, channel string, id int, tags []string, actor string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags given")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		return nil, fmt.Errorf("no quote with id %d found", id)
+	}
+	var added []string
+	for _, tag := range tags {
+		if !containsString(q.Tags, tag) {
+			added = append(added, tag)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	err = s.mutateLocked(channel, id, actor, RevisionTag, func(q *memQuote, rev *Revision) {
+		q.Tags = append(q.Tags, added...)
+		rev.Note = formatTags(added)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveTags implements QuoteRepository.
+func (s *MemoryStore) RemoveTags(_ context.Context, channel string, id int, tags []string, actor string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags given")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		return nil, fmt.Errorf("no quote with id %d found", id)
+	}
+	var removed, kept []string
+	for _, tag := range q.Tags {
+		if containsString(tags, tag) {
+			removed = append(removed, tag)
+		} else {
+			kept = append(kept, tag)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	err = s.mutateLocked(channel, id, actor, RevisionUntag, func(q *memQuote, rev *Revision) {
+		q.Tags = kept
+		rev.Note = formatTags(removed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// TagsFor implements QuoteRepository.
+func (s *MemoryStore) TagsFor(_ context.Context, channel string, id int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		q = s.findLocked(channel, id, true)
+	}
+	if q == nil {
+		return nil, nil
+	}
+	tags := append([]string(nil), q.Tags...)
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// TopTags implements QuoteRepository.
+func (s *MemoryStore) TopTags(_ context.Context, channel string, limit int) ([]TagCount, error) {
+	s.mu.Lock()
+	uses := make(map[string]int)
+	for _, q := range s.liveLocked(channel) {
+		for _, tag := range q.Tags {
+			uses[tag]++
+		}
+	}
+	s.mu.Unlock()
+
+	if len(uses) == 0 {
+		return nil, ErrNoQuotes
+	}
+	counts := make([]TagCount, 0, len(uses))
+	for name, count := range uses {
+		counts = append(counts, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if limit > 0 && limit < len(counts) {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
+
+// History implements QuoteRepository.
+func (s *MemoryStore) History(_ context.Context, channel string, quoteID, limit int) ([]Revision, error) {
+	channel = normalizeChannel(channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revisions []Revision
+	for i := len(s.revisions) - 1; i >= 0; i-- {
+		rev := s.revisions[i]
+		if rev.Channel != channel || rev.QuoteID != quoteID {
+			continue
+		}
+		revisions = append(revisions, rev.Revision)
+		if limit > 0 && len(revisions) == limit {
+			break
+		}
+	}
+	if len(revisions) == 0 {
+		return nil, ErrNoQuotes
+	}
+	return revisions, nil
+}
+
+// Revert implements QuoteRepository.
+func (s *MemoryStore) Revert(_ context.Context, channel string, quoteID, revisionID int, actor string) error {
+	channel = normalizeChannel(channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var target *Revision
+	for i := range s.revisions {
+		rev := &s.revisions[i].Revision
+		if rev.ID == revisionID && rev.Channel == channel && rev.QuoteID == quoteID {
+			target = rev
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no revision %d found for quote #%d", revisionID, quoteID)
+	}
+	newText, newAuthor := target.NewText, target.NewAuthor
+	if old := s.findLocked(channel, quoteID, false); old != nil {
+		if err := s.duplicateLocked(channel, quoteFingerprint(newText), old.RowID); err != nil {
+			return err
+		}
+	}
+	return s.mutateLocked(channel, quoteID, actor, RevisionRevert, func(q *memQuote, rev *Revision) {
+		q.Text = newText
+		q.Author = newAuthor
+		rev.NewText = newText
+		rev.NewAuthor = newAuthor
+		rev.Note = fmt.Sprintf("reverted to revision %d", revisionID)
+	})
+}
+
+// Vote implements QuoteRepository.
+func (s *MemoryStore) Vote(_ context.Context, channel string, id int, voter string, value int) (VoteResult, error) {
+	if err := validateVote(voter, value); err != nil {
+		return VoteResult{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		return VoteResult{}, fmt.Errorf("no quote with id %d found", id)
+	}
+	result := VoteResult{Score: q.Score, Previous: q.Votes[voter]}
+	if result.Previous == value {
+		return result, nil
+	}
+	voted := q.clone()
+	if voted.Votes == nil {
+		voted.Votes = make(map[string]int)
+	}
+	voted.Votes[voter] = value
+	voted.Score = q.Score - result.Previous + value
+	if err := s.commitLocked(quoteRecord(voted)); err != nil {
+		return VoteResult{}, err
+	}
+	result.Score = voted.Score
+	result.Changed = true
+	return result, nil
+}
+
+// RecordView implements QuoteRepository.
+func (s *MemoryStore) RecordView(_ context.Context, channel string, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.findLocked(channel, id, false)
+	if q == nil {
+		return fmt.Errorf("no quote with id %d found", id)
+	}
+	return s.commitLocked(shownRecord(memRecordView, q.RowID, time.Now().UTC()))
+}
+
+// Stats implements QuoteRepository.
+func (s *MemoryStore) Stats(_ context.Context, channel string, limit int) (QuoteStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live := s.liveLocked(channel)
+	if len(live) == 0 {
+		return QuoteStats{}, ErrNoQuotes
+	}
+
+	stats := QuoteStats{Total: len(live)}
+	authors := make(map[string]*AuthorCount)
+	months := make(map[time.Time]int)
+	var shown []Quote
+	for _, q := range live {
+		key := strings.ToLower(q.Author)
+		if ac, ok := authors[key]; ok {
+			ac.Count++
+			if q.Author < ac.Name {
+				ac.Name = q.Author
+			}
+		} else {
+			authors[key] = &AuthorCount{Name: q.Author, Count: 1}
+		}
+		created := q.CreatedAt.UTC()
+		months[time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, time.UTC)]++
+		stats.Views += q.Views
+		if q.Views == 0 {
+			stats.NeverShown++
+		} else {
+			shown = append(shown, q.Quote)
+		}
+		if !q.LastShownAt.IsZero() && (stats.LastShown == nil || q.LastShownAt.After(stats.LastShown.LastShownAt)) {
+			last := q.Quote
+			stats.LastShown = &last
+		}
+	}
+	stats.Authors = len(authors)
+
+	for _, ac := range authors {
+		stats.TopAuthors = append(stats.TopAuthors, *ac)
+	}
+	sort.Slice(stats.TopAuthors, func(i, j int) bool {
+		a, b := stats.TopAuthors[i], stats.TopAuthors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	sort.SliceStable(shown, func(i, j int) bool { return shown[i].Views > shown[j].Views })
+	if limit > 0 {
+		stats.TopAuthors = stats.TopAuthors[:min(limit, len(stats.TopAuthors))]
+		shown = shown[:min(limit, len(shown))]
+	}
+	stats.MostShown = shown
+
+	for month, count := range months {
+		stats.AddedPerMonth = append(stats.AddedPerMonth, MonthCount{Month: month, Count: count})
+	}
+	sort.Slice(stats.AddedPerMonth, func(i, j int) bool { return stats.AddedPerMonth[i].Month.Before(stats.AddedPerMonth[j].Month) })
+	return stats, nil
+}
+
+// waitingLocked returns the submissions still waiting in channel's queue, oldest first. The caller
+// must hold s.mu.
+func (s *MemoryStore) waitingLocked(channel string) []*PendingQuote {
+	channel = normalizeChannel(channel)
+	var waiting []*PendingQuote
+	for _, p := range s.pending {
+		if p.Channel == channel && p.Status == PendingWaiting {
+			waiting = append(waiting, p)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].ID < waiting[j].ID })
+	return waiting
+}
+
+// findWaitingLocked returns the waiting submission id in channel, or an error when there is none.
+// The caller must hold s.mu.
+func (s *MemoryStore) findWaitingLocked(channel string, id int) (PendingQuote, error) {
+	p, ok := s.pending[id]
+	if !ok || p.Channel != normalizeChannel(channel) || p.Status != PendingWaiting {
+		return PendingQuote{}, fmt.Errorf("no pending quote with id %d found", id)
+	}
+	reviewed := *p
+	reviewed.Tags = append([]string(nil), p.Tags...)
+	return reviewed, nil
+}
+
+// Submit implements QuoteRepository.
+func (s *MemoryStore) Submit(_ context.Context, channel string, nq NewQuote) (PendingQuote, error) {
+	p, err := newPendingQuote(channel, nq)
+	if err != nil {
+		return PendingQuote{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fingerprint := quoteFingerprint(p.Text); fingerprint != "" {
+		for _, q := range s.liveLocked(p.Channel) {
+			if quoteFingerprint(q.Text) == fingerprint {
+				return PendingQuote{}, &DuplicateQuoteError{ExistingID: q.ID}
+			}
+		}
+		for _, w := range s.waitingLocked(p.Channel) {
+			if quoteFingerprint(w.Text) == fingerprint {
+				return PendingQuote{}, &DuplicateQuoteError{ExistingID: w.ID, Pending: true}
+			}
+		}
+	}
+	p.ID = s.lastPendingID + 1
+	if err := s.commitLocked(memRecord{Op: memRecordPending, Pending: &p}); err != nil {
+		return PendingQuote{}, err
+	}
+	return p, nil
+}
+
+// Pending implements QuoteRepository.
+func (s *MemoryStore) Pending(_ context.Context, channel string, limit int) ([]PendingQuote, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waiting := s.waitingLocked(channel)
+	if len(waiting) == 0 {
+		return nil, 0, ErrNoQuotes
+	}
+	total := len(waiting)
+	if limit > 0 && len(waiting) > limit {
+		waiting = waiting[:limit]
+	}
+	pending := make([]PendingQuote, len(waiting))
+	for i, p := range waiting {
+		pending[i] = *p
+		pending[i].Tags = append([]string(nil), p.Tags...)
+	}
+	return pending, total, nil
+}
+
+// Approve implements QuoteRepository.
+func (s *MemoryStore) Approve(_ context.Context, channel string, id int, actor string) (AddResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.findWaitingLocked(channel, id)
+	if err != nil {
+		return AddResult{}, err
+	}
+	result, q, rev, err := s.prepareAddLocked(channel, p.newQuote())
+	if err != nil {
+		return AddResult{}, err
+	}
+	p.Status = PendingApproved
+	p.ReviewedBy = actor
+	p.ReviewedAt = time.Now().UTC()
+	p.QuoteID = q.ID
+	records := append([]memRecord{quoteRecord(q)}, s.revisionRecordsLocked(rev, approvalRevision(q.Quote, actor, id))...)
+	if err := s.commitLocked(append(records, memRecord{Op: memRecordPending, Pending: &p})...); err != nil {
+		return AddResult{}, err
+	}
+	return result, nil
+}
+
+// Reject implements QuoteRepository.
+func (s *MemoryStore) Reject(_ context.Context, channel string, id int, actor, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.findWaitingLocked(channel, id)
+	if err != nil {
+		return err
+	}
+	p.Status = PendingRejected
+	p.ReviewedBy = actor
+	p.ReviewedAt = time.Now().UTC()
+	p.Reason = strings.TrimSpace(reason)
+	return s.commitLocked(memRecord{Op: memRecordPending, Pending: &p})
+}
+
+// AdoptUnscopedQuotes implements QuoteRepository.
+func (s *MemoryStore) AdoptUnscopedQuotes(_ context.Context, channel string) (int64, error) {
+	channel = normalizeChannel(channel)
+	if channel == "" {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.adopted {
+		return 0, nil
+	}
+	var adopted int64
+	for _, q := range s.quotes {
+		if q.Channel == "" {
+			adopted++
+		}
+	}
+	if err := s.commitLocked(memRecord{Op: memRecordAdopt, Channel: channel, Seq: s.counters[channel]}); err != nil {
+		return 0, err
+	}
+	return adopted, nil
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
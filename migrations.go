@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// migration is a single numbered schema change. Versions must be unique and strictly increasing;
+// once released a migration must never be edited, only followed by a new one.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// MigrationStatus reports whether a known migration has been applied to the database.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer build than this binary.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// migrations lists every schema change in order. Append new entries at the end.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create quotes table",
+		up: execStatements(`CREATE TABLE IF NOT EXISTS quotes (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                text TEXT NOT NULL,
+                author TEXT NOT NULL,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        );`),
+	},
+	{
+		version: 2,
+		name:    "soft delete quotes into a trash bin",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN deleted_at DATETIME`,
+			`ALTER TABLE quotes ADD COLUMN deleted_by TEXT`,
+			`CREATE INDEX IF NOT EXISTS idx_quotes_deleted_at ON quotes(deleted_at)`,
+		),
+	},
+	{
+		version: 3,
+		name:    "append-only quote revision history",
+		up: execStatements(
+			`CREATE TABLE IF NOT EXISTS quote_revisions (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                quote_id INTEGER NOT NULL,
+                actor TEXT NOT NULL,
+                action TEXT NOT NULL,
+                old_text TEXT NOT NULL DEFAULT '',
+                old_author TEXT NOT NULL DEFAULT '',
+                new_text TEXT NOT NULL DEFAULT '',
+                new_author TEXT NOT NULL DEFAULT '',
+                note TEXT NOT NULL DEFAULT '',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+			`CREATE INDEX IF NOT EXISTS idx_quote_revisions_quote ON quote_revisions(quote_id, id)`,
+		),
+	},
+	{
+		version: 4,
+		name:    "fts5 full-text index over quotes",
+		up: execStatements(
+			`CREATE VIRTUAL TABLE IF NOT EXISTS quotes_fts USING fts5(
+                text, author,
+                content='quotes', content_rowid='id',
+                tokenize='unicode61 remove_diacritics 2'
+        )`,
+			`CREATE TRIGGER IF NOT EXISTS quotes_fts_insert AFTER INSERT ON quotes BEGIN
+                INSERT INTO quotes_fts(rowid, text, author) VALUES (new.id, new.text, new.author);
+        END`,
+			`CREATE TRIGGER IF NOT EXISTS quotes_fts_delete AFTER DELETE ON quotes BEGIN
+                INSERT INTO quotes_fts(quotes_fts, rowid, text, author) VALUES ('delete', old.id, old.text, old.author);
+        END`,
+			`CREATE TRIGGER IF NOT EXISTS quotes_fts_update AFTER UPDATE OF text, author ON quotes BEGIN
+                INSERT INTO quotes_fts(quotes_fts, rowid, text, author) VALUES ('delete', old.id, old.text, old.author);
+                INSERT INTO quotes_fts(rowid, text, author) VALUES (new.id, new.text, new.author);
+        END`,
+			`INSERT INTO quotes_fts(quotes_fts) VALUES ('rebuild')`,
+		),
+	},
+	{
+		version: 5,
+		name:    "quote tags",
+		up: execStatements(
+			`CREATE TABLE IF NOT EXISTS tags (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                name TEXT NOT NULL UNIQUE
+        )`,
+			`CREATE TABLE IF NOT EXISTS quote_tags (
+                quote_id INTEGER NOT NULL,
+                tag_id INTEGER NOT NULL,
+                PRIMARY KEY (quote_id, tag_id)
+        )`,
+			`CREATE INDEX IF NOT EXISTS idx_quote_tags_tag ON quote_tags(tag_id)`,
+		),
+	},
+	{
+		version: 6,
+		name:    "capture stream category and title",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN game TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE quotes ADD COLUMN stream_title TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_quotes_game ON quotes(game COLLATE NOCASE)`,
+		),
+	},
+	{
+		// Existing quotes keep their numbers under the empty channel until AdoptUnscopedQuotes
+		// moves them to the configured channel, which the schema alone cannot know.
+		version: 7,
+		name:    "scope quotes to channels",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN channel TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE quotes ADD COLUMN channel_seq INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE quotes SET channel_seq = id`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_quotes_channel_seq ON quotes(channel, channel_seq)`,
+			`CREATE TABLE IF NOT EXISTS channel_counters (
+                channel TEXT PRIMARY KEY,
+                last_seq INTEGER NOT NULL
+        )`,
+			`INSERT INTO channel_counters(channel, last_seq) SELECT '', seq FROM sqlite_sequence WHERE name = 'quotes'`,
+			`ALTER TABLE quote_revisions ADD COLUMN channel TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE quote_revisions ADD COLUMN channel_seq INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE quote_revisions SET channel_seq = quote_id`,
+			`CREATE INDEX IF NOT EXISTS idx_quote_revisions_channel_seq ON quote_revisions(channel, channel_seq)`,
+		),
+	},
+	{
+		version: 8,
+		name:    "track shown quotes for no-repeat random",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN last_shown_at DATETIME`,
+			`CREATE TABLE IF NOT EXISTS shuffle_drawn (
+                quote_id INTEGER PRIMARY KEY
+        )`,
+		),
+	},
+	{
+		version: 9,
+		name:    "record who submitted each quote",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN submitted_by TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE quotes ADD COLUMN submitter_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE quotes ADD COLUMN platform TEXT NOT NULL DEFAULT ''`,
+			// The add revision already knows who submitted older quotes; only the CLI used a fixed actor name.
+			`UPDATE quotes SET submitted_by = (
+                SELECT actor FROM quote_revisions
+                WHERE quote_revisions.quote_id = quotes.id AND quote_revisions.action = 'add' AND quote_revisions.actor <> 'unknown'
+                ORDER BY quote_revisions.id LIMIT 1
+        ) WHERE EXISTS (
+                SELECT 1 FROM quote_revisions
+                WHERE quote_revisions.quote_id = quotes.id AND quote_revisions.action = 'add' AND quote_revisions.actor <> 'unknown'
+        )`,
+			`UPDATE quotes SET platform = CASE submitted_by WHEN 'CLI' THEN 'cli' ELSE 'twitch' END WHERE submitted_by <> ''`,
+			`CREATE INDEX IF NOT EXISTS idx_quotes_submitted_by ON quotes(channel, submitted_by COLLATE NOCASE)`,
+		),
+	},
+	{
+		version: 10,
+		name:    "fingerprint quotes for duplicate detection",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			err := execStatements(
+				`ALTER TABLE quotes ADD COLUMN fingerprint TEXT NOT NULL DEFAULT ''`,
+				`CREATE INDEX IF NOT EXISTS idx_quotes_fingerprint ON quotes(channel, fingerprint)`,
+			)(ctx, tx)
+			if err != nil {
+				return err
+			}
+			return backfillFingerprints(ctx, tx)
+		},
+	},
+	{
+		version: 11,
+		name:    "viewer votes",
+		up: execStatements(
+			`CREATE TABLE IF NOT EXISTS quote_votes (
+                quote_id INTEGER NOT NULL,
+                voter TEXT NOT NULL,
+                value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY (quote_id, voter)
+        )`,
+			`ALTER TABLE quotes ADD COLUMN score INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX IF NOT EXISTS idx_quotes_score ON quotes(channel, score)`,
+		),
+	},
+	{
+		version: 12,
+		name:    "count quote views",
+		up: execStatements(
+			`ALTER TABLE quotes ADD COLUMN view_count INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX IF NOT EXISTS idx_quotes_view_count ON quotes(channel, view_count)`,
+		),
+	},
+	{
+		version: 13,
+		name:    "store timestamps in UTC",
+		// datetime() converts values written with a zone offset or in RFC 3339 form to the UTC
+		// "YYYY-MM-DD HH:MM:SS" layout, so timestamps compare correctly as text.
+		up: execStatements(
+			utcTimestamps("quotes", "created_at"),
+			utcTimestamps("quotes", "deleted_at"),
+			utcTimestamps("quotes", "last_shown_at"),
+			utcTimestamps("quote_revisions", "created_at"),
+			utcTimestamps("quote_votes", "created_at"),
+		),
+	},
+	{
+		version: 14,
+		name:    "approval queue",
+		up: execStatements(
+			`CREATE TABLE IF NOT EXISTS pending_quotes (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                channel TEXT NOT NULL,
+                text TEXT NOT NULL,
+                author TEXT NOT NULL,
+                tags TEXT NOT NULL DEFAULT '',
+                game TEXT NOT NULL DEFAULT '',
+                stream_title TEXT NOT NULL DEFAULT '',
+                submitted_by TEXT NOT NULL DEFAULT '',
+                submitter_id TEXT NOT NULL DEFAULT '',
+                platform TEXT NOT NULL DEFAULT '',
+                fingerprint TEXT NOT NULL DEFAULT '',
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                status TEXT NOT NULL DEFAULT 'pending',
+                reviewed_by TEXT NOT NULL DEFAULT '',
+                reviewed_at DATETIME,
+                reason TEXT NOT NULL DEFAULT '',
+                quote_id INTEGER
+        )`,
+			`CREATE INDEX IF NOT EXISTS idx_pending_quotes_channel_status ON pending_quotes(channel, status)`,
+		),
+	},
+	{
+		// store_flags records one-off work done on a database, so AdoptUnscopedQuotes runs once.
+		version: 15,
+		name:    "store flags",
+		up: execStatements(
+			`CREATE TABLE IF NOT EXISTS store_flags (
+                name TEXT PRIMARY KEY,
+                set_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		),
+	},
+}
+
+// utcTimestamps returns a statement rewriting column of table in SQLite's UTC timestamp layout.
+// Values SQLite cannot parse are left alone.
+func utcTimestamps(table, column string) string {
+	return fmt.Sprintf("UPDATE %[1]s SET %[2]s = datetime(%[2]s) WHERE datetime(%[2]s) IS NOT NULL AND %[2]s <> datetime(%[2]s)", table, column)
+}
+
+// execStatements returns a migration step that executes each statement in order.
+func execStatements(statements ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// latestSchemaVersion returns the highest migration version known to this binary.
+func latestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// ensureMigrationsTable creates the bookkeeping table that records applied migrations.
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	const query = `CREATE TABLE IF NOT EXISTS schema_migrations (
+                version INTEGER PRIMARY KEY,
+                name TEXT NOT NULL,
+                applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        );`
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied_at timestamp of every recorded migration keyed by version.
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scanning schema_migrations: %w", err)
+		}
+		parsed, err := parseSQLiteTime(appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = parsed
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// checkSchemaVersion refuses to continue when the database contains migrations this binary does not know about.
+func checkSchemaVersion(applied map[int]time.Time) error {
+	latest := latestSchemaVersion()
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w (database version %d, binary supports up to %d)", ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+// migrateUp applies every pending migration, each inside its own transaction together with its
+// schema_migrations bookkeeping row. It returns the versions that were applied.
+func migrateUp(ctx context.Context, db *sql.DB) ([]int, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return done, err
+		}
+		done = append(done, m.version)
+	}
+	return done, nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(ctx, tx); err != nil {
+		return fmt.Errorf("applying migration %d (%s): %w", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, name) VALUES(?, ?)", m.version, m.name); err != nil {
+		return fmt.Errorf("recording migration %d: %w", m.version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// MigrationStatus lists every migration known to this binary and whether it has been applied.
+func (s *QuoteStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(ctx, s.db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   m.version,
+			Name:      m.name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies any pending migrations and returns the versions that were applied.
+func (s *QuoteStore) MigrateUp(ctx context.Context) ([]int, error) {
+	return migrateUp(ctx, s.db)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Role is a sender's standing in a channel's chat, from least to most trusted. A subcommand that
+// needs a role can be run by senders holding it or any role above it.
+type Role int
+
+const (
+	// RoleEveryone is anyone in chat.
+	RoleEveryone Role = iota
+	// RoleFollower is a viewer who has followed the channel for at least its follower_min_days.
+	// Twitch does not send it with messages, so it is looked up through Helix when a subcommand
+	// needs it.
+	RoleFollower
+	// RoleSubscriber is a subscriber or founder of the channel.
+	RoleSubscriber
+	// RoleVIP is a VIP of the channel.
+	RoleVIP
+	// RoleModerator is a Twitch moderator of the channel.
+	RoleModerator
+	// RoleBroadcaster is the channel's owner.
+	RoleBroadcaster
+)
+
+// roleNames are the names of the roles in configuration, in Role order.
+var roleNames = []string{"everyone", "follower", "subscriber", "vip", "moderator", "broadcaster"}
+
+// String returns the role's name in configuration.
+func (r Role) String() string {
+	if r < 0 || int(r) >= len(roleNames) {
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+// parseRole reads a role name from configuration, accepting plurals and common short forms.
+func parseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "everyone", "all", "viewer", "viewers":
+		return RoleEveryone, nil
+	case "follower", "followers":
+		return RoleFollower, nil
+	case "subscriber", "subscribers", "sub", "subs":
+		return RoleSubscriber, nil
+	case "vip", "vips":
+		return RoleVIP, nil
+	case "moderator", "moderators", "mod", "mods":
+		return RoleModerator, nil
+	case "broadcaster", "streamer", "owner":
+		return RoleBroadcaster, nil
+	default:
+		return RoleEveryone, fmt.Errorf("unknown role %q (use %s)", name, strings.Join(roleNames, ", "))
+	}
+}
+
+// plural names the senders who hold r, for replies such as "Only Twitch moderators can ...".
+func (r Role) plural() string {
+	switch r {
+	case RoleFollower:
+		return "followers"
+	case RoleSubscriber:
+		return "subscribers"
+	case RoleVIP:
+		return "VIPs"
+	case RoleModerator:
+		return "Twitch moderators"
+	case RoleBroadcaster:
+		return "the broadcaster"
+	default:
+		return "everyone"
+	}
+}
+
+// allCommands is the key in allow and deny lists that applies to every subcommand.
+const allCommands = "*"
+
+// FollowerLookup reports when a user followed a channel, for subcommands limited to followers.
+type FollowerLookup interface {
+	// FollowedAt returns when the user with the given Twitch ID followed channel, and false when
+	// they do not follow it.
+	FollowedAt(ctx context.Context, channel, userID string) (time.Time, bool, error)
+}
+
+// CommandPermissions decides who may run which subcommands in a channel. The zero value applies
+// each subcommand's own role to everyone.
+type CommandPermissions struct {
+	// FollowerMinAge is how long a viewer must have followed the channel to count as a follower.
+	FollowerMinAge time.Duration
+	// roles overrides the minimum role of subcommands, by name.
+	roles map[string]Role
+	// allowed and denied map a subcommand name, or "*" for all of them, to the lower-case logins
+	// listed for it.
+	allowed map[string]map[string]bool
+	denied  map[string]map[string]bool
+}
+
+// parseCommandPermissions validates a channel's permission settings against the subcommands in
+// registry. Subcommands may be named by any of their aliases.
+func parseCommandPermissions(roles map[string]string, allowed, denied map[string][]string, followerMinDays int, registry *CommandRegistry) (CommandPermissions, error) {
+	if followerMinDays < 0 {
+		return CommandPermissions{}, fmt.Errorf("follower_min_days must not be negative")
+	}
+	p := CommandPermissions{
+		FollowerMinAge: time.Duration(followerMinDays) * 24 * time.Hour,
+		roles:          make(map[string]Role),
+	}
+	for name, roleName := range roles {
+		name, err := canonicalCommand(registry, "command_roles", name)
+		if err != nil {
+			return CommandPermissions{}, err
+		}
+		role, err := parseRole(roleName)
+		if err != nil {
+			return CommandPermissions{}, fmt.Errorf("command_roles: %s: %w", name, err)
+		}
+		p.roles[name] = role
+	}
+	var err error
+	if p.allowed, err = parseUserLists("allowed_users", allowed, registry); err != nil {
+		return CommandPermissions{}, err
+	}
+	if p.denied, err = parseUserLists("denied_users", denied, registry); err != nil {
+		return CommandPermissions{}, err
+	}
+	return p, nil
+}
+
+// parseUserLists canonicalizes the subcommand names and user logins of an allow or deny list.
+func parseUserLists(setting string, lists map[string][]string, registry *CommandRegistry) (map[string]map[string]bool, error) {
+	parsed := make(map[string]map[string]bool)
+	for name, users := range lists {
+		if name = strings.TrimSpace(name); name != allCommands {
+			var err error
+			if name, err = canonicalCommand(registry, setting, name); err != nil {
+				return nil, err
+			}
+		}
+		if parsed[name] == nil {
+			parsed[name] = make(map[string]bool)
+		}
+		for _, user := range users {
+			login := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(user), "@"))
+			if login == "" {
+				return nil, fmt.Errorf("%s: empty user name for %s", setting, name)
+			}
+			parsed[name][login] = true
+		}
+	}
+	return parsed, nil
+}
+
+// Role returns the minimum role needed to run the subcommand described by info in the channel.
+func (p CommandPermissions) Role(info CommandInfo) Role {
+	if role, ok := p.roles[info.Name]; ok {
+		return role
+	}
+	return info.Role
+}
+
+// Allowed reports whether sender is listed in allowed_users for the subcommand called name, which
+// lets them run it whatever their role.
+func (p CommandPermissions) Allowed(name string, sender Sender) bool {
+	return listsSender(p.allowed, name, sender)
+}
+
+// Denied reports whether sender is listed in denied_users for the subcommand called name. A denied
+// sender cannot run it even when also allowed.
+func (p CommandPermissions) Denied(name string, sender Sender) bool {
+	return listsSender(p.denied, name, sender)
+}
+
+// listsSender reports whether lists names sender for the subcommand called name or for all of them.
+func listsSender(lists map[string]map[string]bool, name string, sender Sender) bool {
+	login := strings.ToLower(firstNonEmpty(sender.Login, sender.Name))
+	if login == "" {
+		return false
+	}
+	return lists[name][login] || lists[allCommands][login]
+}
+
+// permits reports whether sender may run the subcommand described by info in channel, looking up
+// whether they follow the channel only when the subcommand is limited to followers.
+func (h *CommandHandler) permits(ctx context.Context, channel string, sender Sender, info CommandInfo, perms CommandPermissions) bool {
+	role := perms.Role(info)
+	if sender.Role >= role || perms.Allowed(info.Name, sender) {
+		return true
+	}
+	if role != RoleFollower || h.follows == nil || sender.ID == "" {
+		return false
+	}
+	followed, ok, err := h.follows.FollowedAt(ctx, channel, sender.ID)
+	if err != nil {
+		log.Printf("Error checking whether %s follows #%s: %v", sender.Name, channel, err)
+		return false
+	}
+	return ok && time.Since(followed) >= perms.FollowerMinAge
+}
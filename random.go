@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultFreshDays is how long a quote counts as recently shown for "!quote random fresh" when the
+// config does not say otherwise.
+const defaultFreshDays = 7
+
+// RandomWeight selects how quotes are weighted within a shuffle-bag cycle.
+type RandomWeight string
+
+const (
+	// RandomWeightNone picks uniformly among the quotes left in the bag.
+	RandomWeightNone RandomWeight = ""
+	// RandomWeightAge favours older quotes, so long-standing classics tend to come up early in
+	// each cycle. A quote's weight grows by one for every 30 days since it was added.
+	RandomWeightAge RandomWeight = "age"
+	// RandomWeightScore favours quotes chat voted up: a quote's weight grows by one for every net
+	// upvote and shrinks for quotes voted down (see scoreWeight).
+	RandomWeightScore RandomWeight = "score"
+)
+
+// parseRandomWeight validates a configured weighting name.
+func parseRandomWeight(name string) (RandomWeight, error) {
+	switch weight := RandomWeight(strings.ToLower(strings.TrimSpace(name))); weight {
+	case RandomWeightNone, "none", "uniform":
+		return RandomWeightNone, nil
+	case RandomWeightAge, RandomWeightScore:
+		return weight, nil
+	case "rating", "votes":
+		return RandomWeightScore, nil
+	default:
+		return RandomWeightNone, fmt.Errorf("unknown random weighting %q (use none, age or score)", name)
+	}
+}
+
+// RandomSettings holds the configured defaults applied to every random pick.
+type RandomSettings struct {
+	Weight RandomWeight
+	// FreshWithin is the window used by the "fresh" variant: quotes shown more recently than this
+	// are only picked when nothing older is available.
+	FreshWithin time.Duration
+}
+
+// RandomOptions narrows the pool Random picks from. The zero value picks from every live quote.
+type RandomOptions struct {
+	// Tag restricts the pick to quotes carrying this tag.
+	Tag string
+	// Game restricts the pick to quotes captured while this game/category was on stream (case-insensitive).
+	Game string
+	// Author restricts the pick to quotes said by this author (case-insensitive).
+	Author string
+	// SubmittedBy restricts the pick to quotes added by this user (case-insensitive display name).
+	SubmittedBy string
+	// Weight biases the pick among the quotes left in the shuffle bag.
+	Weight RandomWeight
+	// FreshWithin, when positive, prefers quotes that have not been shown within this window.
+	FreshWithin time.Duration
+}
+
+// filter returns the SQL condition (to AND onto liveInChannel) and arguments for the options.
+func (o RandomOptions) filter() (string, []any, error) {
+	var conds []string
+	var args []any
+	if o.Tag != "" {
+		tag, ok := normalizeTag(o.Tag)
+		if !ok {
+			return "", nil, fmt.Errorf("invalid tag %q", o.Tag)
+		}
+		conds = append(conds, "quotes.id IN (SELECT quote_tags.quote_id FROM quote_tags JOIN tags ON tags.id = quote_tags.tag_id WHERE tags.name = ?)")
+		args = append(args, tag)
+	}
+	if game := strings.TrimSpace(o.Game); game != "" {
+		conds = append(conds, "quotes.game = ? COLLATE NOCASE")
+		args = append(args, game)
+	}
+	if author := strings.TrimSpace(o.Author); author != "" {
+		conds = append(conds, "quotes.author = ? COLLATE NOCASE")
+		args = append(args, author)
+	}
+	if submitter := strings.TrimSpace(o.SubmittedBy); submitter != "" {
+		conds = append(conds, "quotes.submitted_by = ? COLLATE NOCASE")
+		args = append(args, submitter)
+	}
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " AND " + strings.Join(conds, " AND "), args, nil
+}
+
+// notDrawn excludes quotes already drawn from the shuffle bag in the current cycle.
+const notDrawn = " AND quotes.id NOT IN (SELECT quote_id FROM shuffle_drawn)"
+
+// randomCandidate is a quote eligible for a random pick, with what weighting needs to know about it.
+type randomCandidate struct {
+	rowID   int64
+	created time.Time
+	score   int
+}
+
+// Random returns a random live quote in channel matching opts. Picks are drawn from a persisted
+// shuffle bag, so every matching quote is shown once before any of them repeats; when the bag is
+// empty it is refilled with the matching quotes. With opts.FreshWithin set, quotes not shown within
+// that window are preferred, falling back to the normal bag when every match was shown recently.
+func (s *QuoteStore) Random(ctx context.Context, channel string, opts RandomOptions) (*Quote, error) {
+	where, filterArgs, err := opts.filter()
+	if err != nil {
+		return nil, err
+	}
+	pool := liveInChannel + where
+	args := append([]any{normalizeChannel(channel)}, filterArgs...)
+	now := time.Now()
+
+	var picked Quote
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var candidates []randomCandidate
+		var err error
+		if opts.FreshWithin > 0 {
+			const stale = " AND (quotes.last_shown_at IS NULL OR quotes.last_shown_at < ?)"
+			staleArgs := append(append([]any{}, args...), formatSQLiteTime(now.Add(-opts.FreshWithin)))
+			if candidates, err = randomCandidatesTx(ctx, tx, pool+stale+notDrawn, staleArgs); err != nil {
+				return err
+			}
+			if len(candidates) == 0 {
+				if candidates, err = randomCandidatesTx(ctx, tx, pool+stale, staleArgs); err != nil {
+					return err
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			if candidates, err = randomCandidatesTx(ctx, tx, pool+notDrawn, args); err != nil {
+				return err
+			}
+		}
+		if len(candidates) == 0 {
+			// Every matching quote has been drawn: start a new cycle for them.
+			if _, err := tx.ExecContext(ctx, "DELETE FROM shuffle_drawn WHERE quote_id IN (SELECT quotes.id FROM quotes WHERE "+pool+")", args...); err != nil {
+				return fmt.Errorf("refilling shuffle bag: %w", err)
+			}
+			if candidates, err = randomCandidatesTx(ctx, tx, pool, args); err != nil {
+				return err
+			}
+		}
+		if len(candidates) == 0 {
+			return ErrNoQuotes
+		}
+
+		s.randomMu.Lock()
+		roll := s.random.Float64()
+		s.randomMu.Unlock()
+		rowID := pickWeighted(candidates, opts.Weight, now, roll)
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO shuffle_drawn(quote_id) VALUES(?)", rowID); err != nil {
+			return fmt.Errorf("drawing from shuffle bag: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET last_shown_at = ? WHERE id = ?", formatSQLiteTime(now), rowID); err != nil {
+			return fmt.Errorf("recording shown quote: %w", err)
+		}
+		picked, err = scanQuote(tx.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE quotes.id = ?", rowID))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoQuotes
+			}
+			return fmt.Errorf("scanning random quote: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &picked, nil
+}
+
+// randomCandidatesTx loads the quotes matching the condition where.
+func randomCandidatesTx(ctx context.Context, tx *sql.Tx, where string, args []any) ([]randomCandidate, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT quotes.id, quotes.created_at, quotes.score FROM quotes WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []randomCandidate
+	for rows.Next() {
+		var c randomCandidate
+		var created string
+		if err := rows.Scan(&c.rowID, &created, &c.score); err != nil {
+			return nil, fmt.Errorf("scanning quote: %w", err)
+		}
+		if c.created, err = parseSQLiteTime(created); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating quotes: %w", err)
+	}
+	return candidates, nil
+}
+
+// pickWeighted chooses one of candidates (which must not be empty) according to weight, using roll,
+// a uniform random number in [0, 1), to make the choice.
+func pickWeighted(candidates []randomCandidate, weight RandomWeight, now time.Time, roll float64) int64 {
+	weights := make([]float64, len(candidates))
+	var total float64
+	for i, c := range candidates {
+		w := 1.0
+		switch age := now.Sub(c.created); {
+		case weight == RandomWeightAge && age > 0:
+			w += age.Hours() / 24 / 30
+		case weight == RandomWeightScore:
+			w = scoreWeight(c.score)
+		}
+		weights[i] = w
+		total += w
+	}
+
+	target := roll * total
+	for i, w := range weights {
+		if target < w {
+			return candidates[i].rowID
+		}
+		target -= w
+	}
+	return candidates[len(candidates)-1].rowID
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// commandPrefix is the chat command every subcommand hangs off unless a channel configures
+// another one.
+const commandPrefix = "!quote"
+
+// ArgKind is the type of a subcommand argument, which decides how it is parsed.
+type ArgKind int
+
+const (
+	// ArgWord is a single word.
+	ArgWord ArgKind = iota
+	// ArgQuoteID is a quote number, optionally written with a leading "#".
+	ArgQuoteID
+	// ArgPendingID is the number of a submission in the approval queue, optionally written with a
+	// leading "#".
+	ArgPendingID
+	// ArgCount is a whole number of at least 1.
+	ArgCount
+	// ArgText is the rest of the message. It must be the last argument.
+	ArgText
+)
+
+// ArgSpec describes one argument of a subcommand. Name is shown in the generated usage and is
+// the key to read the parsed value from a CommandCall.
+type ArgSpec struct {
+	Name     string
+	Kind     ArgKind
+	Optional bool
+}
+
+// CommandInfo describes a subcommand for dispatch and for the generated help.
+type CommandInfo struct {
+	// Name is what follows !quote to run the subcommand; Aliases are other words that run it in
+	// every channel.
+	Name    string
+	Aliases []string
+	// Role is the minimum role needed to run the subcommand at all; a channel can change it with
+	// command_roles. A use that needs a higher role than the rest of a subcommand is its own
+	// subcommand: setgame (moderators by default) sets the game, and game passes an argument on
+	// to it so both are checked here.
+	Role Role
+	// Args is checked and parsed before the subcommand runs, replying with the usage when a
+	// required argument is missing.
+	Args []ArgSpec
+	// Usage replaces the argument list generated from Args in the help, for subcommands whose
+	// arguments are freer than the schema can say.
+	Usage string
+	// Summary is the one-line description in the help; Details are further lines shown under it.
+	Summary string
+	Details []string
+}
+
+// Command is a !quote subcommand.
+type Command interface {
+	Info() CommandInfo
+	// Run handles the subcommand once its role and arguments have been checked, returning the
+	// replies to send.
+	Run(ctx context.Context, call *CommandCall) []string
+}
+
+// CommandFunc is a Command made of its description and a function to run.
+type CommandFunc struct {
+	CommandInfo
+	Func func(ctx context.Context, call *CommandCall) []string
+}
+
+// Info implements Command.
+func (c CommandFunc) Info() CommandInfo {
+	return c.CommandInfo
+}
+
+// Run implements Command.
+func (c CommandFunc) Run(ctx context.Context, call *CommandCall) []string {
+	return c.Func(ctx, call)
+}
+
+// CommandCall is one use of a subcommand in chat or the CLI.
+type CommandCall struct {
+	Handler *CommandHandler
+	Store   QuoteRepository
+	Channel string
+	Sender  Sender
+	// Prefix is the channel's command prefix, for replies that mention other commands.
+	Prefix string
+	// Name is the name or alias the subcommand was called by, in lower case.
+	Name string
+	// Args holds every word after the subcommand; the parsed values are read with Int and Text.
+	Args []string
+
+	info   CommandInfo
+	values map[string]any
+}
+
+// Usage returns the subcommand's usage line, for replies to arguments the schema cannot check.
+func (c *CommandCall) Usage() string {
+	return c.info.usage(c.Prefix)
+}
+
+// Has reports whether the argument called name was given.
+func (c *CommandCall) Has(name string) bool {
+	_, ok := c.values[name]
+	return ok
+}
+
+// Int returns the number given for the ArgQuoteID, ArgPendingID or ArgCount argument called name,
+// or 0 when it was not given.
+func (c *CommandCall) Int(name string) int {
+	n, _ := c.values[name].(int)
+	return n
+}
+
+// Text returns the ArgWord or ArgText argument called name, or "" when it was not given.
+func (c *CommandCall) Text(name string) string {
+	s, _ := c.values[name].(string)
+	return s
+}
+
+// Reply renders the channel's reply called name with data, the sender's name as user and the
+// subcommand's name as command.
+func (c *CommandCall) Reply(name string, data replyData) []string {
+	return c.ReplyQuote(name, nil, data)
+}
+
+// ReplyQuote renders the channel's reply called name about q like Reply, adding q's fields and q
+// in the channel's quote format. A nil q adds nothing.
+func (c *CommandCall) ReplyQuote(name string, q *Quote, data replyData) []string {
+	if data == nil {
+		data = replyData{}
+	}
+	data["user"] = c.Sender.Name
+	data["command"] = c.info.Name
+	settings := c.Handler.channels.For(c.Channel)
+	if q == nil {
+		return []string{settings.reply(name, data)}
+	}
+	return []string{settings.replyQuote(name, *q, data)}
+}
+
+// usage returns the subcommand's usage line under prefix, e.g. "!quote get <id>".
+func (info CommandInfo) usage(prefix string) string {
+	args := info.Usage
+	if args == "" {
+		parts := make([]string, len(info.Args))
+		for i, arg := range info.Args {
+			if arg.Optional {
+				parts[i] = "[" + arg.Name + "]"
+			} else {
+				parts[i] = "<" + arg.Name + ">"
+			}
+		}
+		args = strings.Join(parts, " ")
+	}
+	return strings.TrimSpace(prefix + " " + info.Name + " " + args)
+}
+
+// parseArgs parses words against the subcommand's schema. When they do not fit, it returns the
+// name of the reply explaining why instead: usage, invalid_id or invalid_pending_id.
+func (info CommandInfo) parseArgs(words []string) (map[string]any, string) {
+	values := make(map[string]any, len(info.Args))
+	for i, arg := range info.Args {
+		if i >= len(words) {
+			if arg.Optional {
+				break
+			}
+			return nil, "usage"
+		}
+		word := words[i]
+		switch arg.Kind {
+		case ArgWord:
+			values[arg.Name] = word
+		case ArgText:
+			values[arg.Name] = strings.Join(words[i:], " ")
+		case ArgQuoteID, ArgPendingID:
+			id, err := strconv.Atoi(strings.TrimPrefix(word, "#"))
+			if err != nil {
+				if arg.Kind == ArgPendingID {
+					return nil, "invalid_pending_id"
+				}
+				return nil, "invalid_id"
+			}
+			values[arg.Name] = id
+		case ArgCount:
+			n, err := strconv.Atoi(word)
+			if err != nil || n < 1 {
+				return nil, "usage"
+			}
+			values[arg.Name] = n
+		}
+	}
+	return values, ""
+}
+
+// validate checks that info can be registered: it needs a name without spaces, a text argument
+// must come last, and required arguments cannot follow optional ones.
+func (info CommandInfo) validate() error {
+	if info.Name == "" || strings.ContainsAny(info.Name, " \t") {
+		return fmt.Errorf("invalid command name %q", info.Name)
+	}
+	optional := false
+	for i, arg := range info.Args {
+		switch {
+		case arg.Name == "":
+			return fmt.Errorf("command %q: argument %d has no name", info.Name, i+1)
+		case arg.Kind == ArgText && i != len(info.Args)-1:
+			return fmt.Errorf("command %q: text argument %q must come last", info.Name, arg.Name)
+		case optional && !arg.Optional:
+			return fmt.Errorf("command %q: required argument %q follows an optional one", info.Name, arg.Name)
+		}
+		optional = optional || arg.Optional
+	}
+	return nil
+}
+
+// CommandRegistry holds the subcommands a CommandHandler dispatches to, keyed by name and alias.
+type CommandRegistry struct {
+	commands []Command
+	byName   map[string]Command
+}
+
+// NewCommandRegistry returns an empty registry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{byName: make(map[string]Command)}
+}
+
+// Register adds cmd, failing when its description is invalid or its name or an alias is taken.
+func (r *CommandRegistry) Register(cmd Command) error {
+	info := cmd.Info()
+	if err := info.validate(); err != nil {
+		return err
+	}
+	names := append([]string{info.Name}, info.Aliases...)
+	for _, name := range names {
+		if _, taken := r.byName[strings.ToLower(name)]; taken {
+			return fmt.Errorf("command %q is already registered", name)
+		}
+	}
+	for _, name := range names {
+		r.byName[strings.ToLower(name)] = cmd
+	}
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// Lookup returns the subcommand called name or one of its aliases.
+func (r *CommandRegistry) Lookup(name string) (Command, bool) {
+	cmd, ok := r.byName[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Commands returns every registered subcommand in the order they were registered.
+func (r *CommandRegistry) Commands() []Command {
+	return append([]Command(nil), r.commands...)
+}
+
+// extraCommands are the subcommands added with RegisterCommand.
+var extraCommands []Command
+
+// RegisterCommand adds cmd to the subcommands of every CommandHandler created afterwards, so a
+// separate file can extend !quote from an init function without touching commands.go. Like
+// http.Handle it panics when the command is invalid or clashes with another one.
+func RegisterCommand(cmd Command) {
+	extra := append(append([]Command(nil), extraCommands...), cmd)
+	if _, err := (&CommandHandler{}).buildRegistry(extra); err != nil {
+		panic(fmt.Sprintf("RegisterCommand: %v", err))
+	}
+	extraCommands = extra
+}
+
+// defaultRegistry returns the subcommands a new CommandHandler starts with, which channel settings
+// are checked against.
+func defaultRegistry() (*CommandRegistry, error) {
+	return (&CommandHandler{}).buildRegistry(extraCommands)
+}
+
+// buildRegistry returns a registry holding the built-in subcommands followed by extra.
+func (h *CommandHandler) buildRegistry(extra []Command) (*CommandRegistry, error) {
+	registry := NewCommandRegistry()
+	for _, cmd := range append(h.builtinCommands(), extra...) {
+		if err := registry.Register(cmd); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds a subcommand to this handler. It fails when the name or an alias is taken, including
+// by a built-in subcommand.
+func (h *CommandHandler) Register(cmd Command) error {
+	return h.commands.Register(cmd)
+}
+
+// dispatch runs the subcommand called name with the words after it, checking the sender's role,
+// the arguments and the cooldowns first. Unknown subcommands get the help, which has the help
+// subcommand's cooldowns; subcommands disabled in channel, and senders denied them, are ignored as
+// if the subcommand did not exist, leaving them to other bots.
+func (h *CommandHandler) dispatch(ctx context.Context, channel, name string, args []string, sender Sender) []string {
+	settings := h.channels.For(channel)
+	triggers := settings.Triggers
+	cmd, ok := h.commands.Lookup(name)
+	if !ok {
+		if replies, ok := h.cooldown(channel, sender, CommandInfo{Name: "help"}, settings.Cooldowns); !ok {
+			return replies
+		}
+		return []string{h.helpFor(channel, "", sender)}
+	}
+	info := cmd.Info()
+	if triggers.Disabled(info.Name) || settings.Permissions.Denied(info.Name, sender) {
+		return nil
+	}
+	prefix := triggers.prefix()
+	if !h.permits(ctx, channel, sender, info, settings.Permissions) {
+		return []string{settings.reply("denied", replyData{
+			"role":    settings.Permissions.Role(info).plural(),
+			"command": info.Name,
+			"user":    sender.Name,
+		})}
+	}
+	values, reply := info.parseArgs(args)
+	if reply != "" {
+		return []string{settings.reply(reply, replyData{"usage": info.usage(prefix), "command": info.Name})}
+	}
+	if replies, ok := h.cooldown(channel, sender, info, settings.Cooldowns); !ok {
+		return replies
+	}
+	return cmd.Run(ctx, &CommandCall{
+		Handler: h,
+		Store:   h.store,
+		Channel: channel,
+		Sender:  sender,
+		Prefix:  prefix,
+		Name:    strings.ToLower(name),
+		Args:    args,
+		info:    info,
+		values:  values,
+	})
+}
+
+// Help returns the usage of every subcommand enabled in channel, or the details of the one called
+// topic, written with the channel's prefix, aliases and roles.
+func (h *CommandHandler) Help(channel, topic string) string {
+	settings := h.channels.For(channel)
+	triggers := settings.Triggers
+	prefix := triggers.prefix()
+	if topic = strings.TrimSpace(topic); topic != "" {
+		name := strings.ToLower(strings.TrimPrefix(topic, prefix+" "))
+		if target, ok := triggers.aliases[name]; ok {
+			name = target
+		}
+		cmd, ok := h.commands.Lookup(name)
+		if !ok || triggers.Disabled(cmd.Info().Name) {
+			return settings.reply("help_unknown", replyData{"topic": topic})
+		}
+		info := cmd.Info()
+		parts := append([]string{helpLine(info, settings.Permissions.Role(info), prefix)}, info.Details...)
+		aliases := append(append([]string(nil), info.Aliases...), triggers.aliasesFor(info.Name)...)
+		if len(aliases) > 0 {
+			sort.Strings(aliases)
+			parts = append(parts, "Also: "+strings.Join(aliases, ", ")+".")
+		}
+		return strings.Join(parts, " ")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Usage:\n")
+	sb.WriteString(prefix + " - Return a random quote.")
+	for _, cmd := range h.commands.Commands() {
+		info := cmd.Info()
+		if triggers.Disabled(info.Name) {
+			continue
+		}
+		sb.WriteString("\n" + helpLine(info, settings.Permissions.Role(info), prefix))
+		for _, detail := range info.Details {
+			sb.WriteString("\n    " + detail)
+		}
+	}
+	return sb.String()
+}
+
+// ChatHelp is Help as a single chat message: the names of the subcommands enabled in channel, or
+// the details of the one called topic, cut to fit Twitch's message limit.
+func (h *CommandHandler) ChatHelp(channel, topic string) string {
+	if strings.TrimSpace(topic) != "" {
+		return truncate(h.Help(channel, topic), twitchMessageLimit-3)
+	}
+	settings := h.channels.For(channel)
+	var names []string
+	for _, cmd := range h.commands.Commands() {
+		if name := cmd.Info().Name; !settings.Triggers.Disabled(name) {
+			names = append(names, name)
+		}
+	}
+	return truncate(settings.reply("help", replyData{"commands": strings.Join(names, ", ")}), twitchMessageLimit-3)
+}
+
+// helpFor returns the multi-line Help to the CLI and ChatHelp to chat senders.
+func (h *CommandHandler) helpFor(channel, topic string, sender Sender) string {
+	if sender.Platform == PlatformCLI {
+		return h.Help(channel, topic)
+	}
+	return h.ChatHelp(channel, topic)
+}
+
+// helpLine formats a subcommand's usage under prefix and its summary, noting the role it is limited
+// to.
+func helpLine(info CommandInfo, role Role, prefix string) string {
+	line := info.usage(prefix) + " - " + info.Summary
+	if role == RoleEveryone {
+		return line
+	}
+	line = strings.TrimSuffix(line, ".")
+	switch role {
+	case RoleModerator:
+		return line + " (Twitch moderator only)."
+	case RoleBroadcaster:
+		return line + " (broadcaster only)."
+	default:
+		return fmt.Sprintf("%s (%s and up).", line, role.plural())
+	}
+}
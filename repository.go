@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuoteRepository is the storage interface consumed by the command handler, CLI and TUI. Every
+// method scopes its work to a channel, except PurgeTrash which applies the retention policy to all
+// channels. Implementations must be safe for concurrent use.
+type QuoteRepository interface {
+	Add(ctx context.Context, channel string, nq NewQuote) (AddResult, error)
+	Random(ctx context.Context, channel string, opts RandomOptions) (*Quote, error)
+	Search(ctx context.Context, channel string, query string, limit int) ([]SearchResult, int, error)
+	List(ctx context.Context, channel string) ([]Quote, error)
+	ListPage(ctx context.Context, channel string, opts ListOptions) (ListPage, error)
+	GetByID(ctx context.Context, channel string, id int) (*Quote, error)
+	Latest(ctx context.Context, channel string) (*Quote, error)
+	Count(ctx context.Context, channel string) (int, error)
+	UpdateText(ctx context.Context, channel string, id int, newText, actor string) error
+	UpdateAuthor(ctx context.Context, channel string, id int, newAuthor, actor string) error
+	Delete(ctx context.Context, channel string, id int, actor string) error
+
+	// Trash.
+	Restore(ctx context.Context, channel string, id int, actor string) error
+	Trash(ctx context.Context, channel string, limit int) ([]Quote, error)
+	GetDeleted(ctx context.Context, channel string, id int) (*Quote, error)
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// Tags.
+	AddTags(ctx context.Context, channel string, id int, tags []string, actor string) ([]string, error)
+	RemoveTags(ctx context.Context, channel string, id int, tags []string, actor string) ([]string, error)
+	TagsFor(ctx context.Context, channel string, id int) ([]string, error)
+	TopTags(ctx context.Context, channel string, limit int) ([]TagCount, error)
+
+	// Votes.
+	Vote(ctx context.Context, channel string, id int, voter string, value int) (VoteResult, error)
+
+	// Views.
+	RecordView(ctx context.Context, channel string, id int) error
+	Stats(ctx context.Context, channel string, limit int) (QuoteStats, error)
+
+	// Bulk writes.
+	AddMany(ctx context.Context, channel string, quotes []NewQuote, opts BulkOptions) ([]int, error)
+	UpdateMany(ctx context.Context, channel string, updates []QuoteUpdate, opts BulkOptions) error
+	DeleteMany(ctx context.Context, channel string, ids []int, opts BulkOptions) error
+
+	// Approval queue.
+	Submit(ctx context.Context, channel string, nq NewQuote) (PendingQuote, error)
+	Pending(ctx context.Context, channel string, limit int) ([]PendingQuote, int, error)
+	Approve(ctx context.Context, channel string, id int, actor string) (AddResult, error)
+	Reject(ctx context.Context, channel string, id int, actor, reason string) error
+
+	// Change events.
+	Subscribe(ctx context.Context, channel string) (<-chan ChangeEvent, error)
+
+	// History.
+	History(ctx context.Context, channel string, quoteID, limit int) ([]Revision, error)
+	Revert(ctx context.Context, channel string, quoteID, revisionID int, actor string) error
+
+	// AdoptUnscopedQuotes moves quotes that predate channel scoping into channel. Only the first
+	// call on a store does anything.
+	AdoptUnscopedQuotes(ctx context.Context, channel string) (int64, error)
+	Close() error
+}
+
+var (
+	_ QuoteRepository = (*QuoteStore)(nil)
+	_ QuoteRepository = (*MemoryStore)(nil)
+	_ QuoteRepository = (*JSONLStore)(nil)
+)
+
+// Store backends selectable with -store or a scheme prefix on the database location.
+const (
+	storeSQLite = "sqlite"
+	storeMemory = "memory"
+	storeJSONL  = "jsonl"
+)
+
+// parseStoreLocation resolves the backend and path from the configured store kind and database
+// location. The location may carry a scheme ("sqlite:quotes.db", "jsonl:quotes.jsonl", "memory:"),
+// which must agree with kind when both are given. A location without a scheme uses kind, and SQLite
+// when kind is empty too.
+func parseStoreLocation(kind, location string) (string, string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	path := strings.TrimSpace(location)
+	if scheme, rest, ok := strings.Cut(path, ":"); ok {
+		switch scheme = strings.ToLower(scheme); scheme {
+		case storeSQLite, storeMemory, storeJSONL:
+			if kind != "" && kind != scheme {
+				return "", "", fmt.Errorf("store %q conflicts with database location %q", kind, location)
+			}
+			kind = scheme
+			path = strings.TrimPrefix(rest, "//")
+		}
+	}
+	if kind == "" {
+		kind = storeSQLite
+	}
+	switch kind {
+	case storeSQLite, storeJSONL:
+		if path == "" {
+			return "", "", fmt.Errorf("the %s store needs a file path", kind)
+		}
+	case storeMemory:
+	default:
+		return "", "", fmt.Errorf("unknown store %q (use sqlite, memory or jsonl)", kind)
+	}
+	return kind, path, nil
+}
+
+// openRepository opens the backend described by kind and location (see parseStoreLocation).
+// sqliteOpts only applies to the SQLite backend.
+func openRepository(ctx context.Context, kind, location string, sqliteOpts SQLiteOptions) (QuoteRepository, error) {
+	kind, path, err := parseStoreLocation(kind, location)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case storeMemory:
+		return NewMemoryStore(), nil
+	case storeJSONL:
+		store, err := OpenJSONLStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		store, err := NewQuoteStore(ctx, path, sqliteOpts)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SearchResult is a quote matched by Search together with its bm25 rank (lower is better) and
+// a snippet of the matching text with hits wrapped in asterisks.
+type SearchResult struct {
+	Quote
+	Rank    float64
+	Snippet string
+}
+
+// ErrInvalidSearch is returned when a search query cannot be parsed.
+var ErrInvalidSearch = errors.New("invalid search query")
+
+// searchNode is a parsed search expression that can be rendered as an FTS5 MATCH expression or
+// evaluated directly against a quote by backends without FTS5.
+type searchNode interface {
+	fts() string
+	matches(doc searchDoc) bool
+}
+
+// searchDoc holds a quote's searchable columns split into lower-case tokens.
+type searchDoc map[string][]string
+
+func newSearchDoc(q Quote) searchDoc {
+	return searchDoc{"text": searchTokens(q.Text), "author": searchTokens(q.Author)}
+}
+
+// searchTokens splits text into lower-case runs of letters and digits. It approximates the FTS5
+// unicode61 tokenizer, except that diacritics are not folded.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchTerm is a single word, prefix, or phrase, optionally restricted to a column.
+type searchTerm struct {
+	column string
+	text   string
+	prefix bool
+}
+
+func (t searchTerm) fts() string {
+	expr := `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+	if t.prefix {
+		expr += " *"
+	}
+	if t.column != "" {
+		expr = t.column + " : " + expr
+	}
+	return expr
+}
+
+func (t searchTerm) matches(doc searchDoc) bool {
+	words := searchTokens(t.text)
+	if len(words) == 0 {
+		return false
+	}
+	columns := []string{"text", "author"}
+	if t.column != "" {
+		columns = []string{t.column}
+	}
+	for _, column := range columns {
+		tokens := doc[column]
+		for start := 0; start+len(words) <= len(tokens); start++ {
+			if t.matchesAt(tokens[start:], words) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAt reports whether tokens begins with the phrase words, treating the last word as a
+// prefix for prefix terms.
+func (t searchTerm) matchesAt(tokens, words []string) bool {
+	for i, word := range words {
+		if t.prefix && i == len(words)-1 {
+			if !strings.HasPrefix(tokens[i], word) {
+				return false
+			}
+		} else if tokens[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// searchOr matches when any child matches.
+type searchOr struct {
+	children []searchNode
+}
+
+func (o searchOr) fts() string {
+	parts := make([]string, len(o.children))
+	for i, child := range o.children {
+		parts[i] = "(" + child.fts() + ")"
+	}
+	return strings.Join(parts, " OR ")
+}
+
+func (o searchOr) matches(doc searchDoc) bool {
+	for _, child := range o.children {
+		if child.matches(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchAnd matches when every include matches and no exclude does.
+type searchAnd struct {
+	include []searchNode
+	exclude []searchNode
+}
+
+func (a searchAnd) fts() string {
+	parts := make([]string, len(a.include))
+	for i, child := range a.include {
+		parts[i] = "(" + child.fts() + ")"
+	}
+	expr := strings.Join(parts, " AND ")
+	if len(a.exclude) > 0 {
+		expr = "(" + expr + ") NOT (" + searchOr{children: a.exclude}.fts() + ")"
+	}
+	return expr
+}
+
+func (a searchAnd) matches(doc searchDoc) bool {
+	for _, child := range a.include {
+		if !child.matches(doc) {
+			return false
+		}
+	}
+	for _, child := range a.exclude {
+		if child.matches(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// includedTerms returns the terms a match must (or may) contain, skipping excluded ones. It is
+// used to rank and highlight matches when FTS5 is not available.
+func includedTerms(node searchNode) []searchTerm {
+	switch n := node.(type) {
+	case searchTerm:
+		return []searchTerm{n}
+	case searchOr:
+		var terms []searchTerm
+		for _, child := range n.children {
+			terms = append(terms, includedTerms(child)...)
+		}
+		return terms
+	case searchAnd:
+		var terms []searchTerm
+		for _, child := range n.include {
+			terms = append(terms, includedTerms(child)...)
+		}
+		return terms
+	default:
+		return nil
+	}
+}
+
+// rankMatch scores a quote that matched node the way the FTS5 query does: lower is better, and a
+// hit in the text counts twice as much as a hit in the author.
+func rankMatch(node searchNode, doc searchDoc) float64 {
+	var score float64
+	for _, term := range includedTerms(node) {
+		if term.column != "author" && term.matches(searchDoc{"text": doc["text"]}) {
+			score += 1.0
+		}
+		if term.column != "text" && term.matches(searchDoc{"author": doc["author"]}) {
+			score += 0.5
+		}
+	}
+	return -score
+}
+
+// highlightWord reports whether word contains a token belonging to one of terms.
+func highlightWord(terms []searchTerm, word string) bool {
+	for _, token := range searchTokens(word) {
+		for _, term := range terms {
+			words := searchTokens(term.text)
+			for i, w := range words {
+				if token == w || (term.prefix && i == len(words)-1 && strings.HasPrefix(token, w)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// snippetMatch renders up to 12 words of text around the first hit, wrapping words that match
+// one of node's terms in asterisks like the FTS5 snippet function.
+func snippetMatch(node searchNode, text string) string {
+	const window = 12
+	var terms []searchTerm
+	for _, term := range includedTerms(node) {
+		if term.column != "author" {
+			terms = append(terms, term)
+		}
+	}
+
+	words := strings.Fields(text)
+	first := -1
+	marked := make([]string, len(words))
+	for i, word := range words {
+		marked[i] = word
+		if highlightWord(terms, word) {
+			marked[i] = "*" + word + "*"
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	start := 0
+	if first > window/2 {
+		start = first - window/2
+	}
+	end := min(start+window, len(words))
+	snippet := strings.Join(marked[start:end], " ")
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(words) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// searchColumns maps the column filters users may type to FTS columns.
+var searchColumns = map[string]string{
+	"author": "author",
+	"by":     "author",
+	"text":   "text",
+}
+
+// parseSearchQuery parses the user-facing search syntax:
+//
+//	word          matches the word anywhere in the text or author
+//	"a phrase"    matches the words in order
+//	pre*          matches any word starting with "pre"
+//	author:name   restricts the following word or phrase to the author
+//	a OR b        either side; AND is implied between adjacent terms
+//	NOT a, -a     excludes quotes matching a
+//	( ... )       groups terms
+func parseSearchQuery(query string) (searchNode, error) {
+	tokens, err := tokenizeSearch(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: query is empty", ErrInvalidSearch)
+	}
+	p := &searchParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidSearch, p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type searchTokenKind int
+
+const (
+	tokenWord searchTokenKind = iota
+	tokenPhrase
+	tokenOpen
+	tokenClose
+)
+
+type searchToken struct {
+	kind   searchTokenKind
+	text   string
+	column string
+	negate bool
+	prefix bool
+}
+
+// tokenizeSearch splits a query into words, phrases and parentheses, attaching column filters,
+// leading "-" negation and trailing "*" prefix markers to the term they apply to.
+func tokenizeSearch(query string) ([]searchToken, error) {
+	var tokens []searchToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, searchToken{kind: tokenOpen, text: "("})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, searchToken{kind: tokenClose, text: ")"})
+			i++
+			continue
+		}
+
+		var tok searchToken
+		if r == '-' {
+			tok.negate = true
+			i++
+		}
+		start := i
+		for i < len(runes) && runes[i] != ':' && runes[i] != '"' && runes[i] != '(' && runes[i] != ')' && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if i < len(runes) && runes[i] == ':' {
+			name := strings.ToLower(string(runes[start:i]))
+			column, ok := searchColumns[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown filter %q", ErrInvalidSearch, name+":")
+			}
+			tok.column = column
+			i++
+			start = i
+			for i < len(runes) && runes[i] != '"' && runes[i] != '(' && runes[i] != ')' && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+		}
+
+		if i == start && i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidSearch)
+			}
+			tok.kind = tokenPhrase
+			tok.text = strings.TrimSpace(string(runes[i+1 : end]))
+			i = end + 1
+			if i < len(runes) && runes[i] == '*' {
+				tok.prefix = true
+				i++
+			}
+		} else {
+			tok.kind = tokenWord
+			tok.text = string(runes[start:i])
+			if strings.HasSuffix(tok.text, "*") {
+				tok.prefix = true
+				tok.text = strings.TrimRight(tok.text, "*")
+			}
+		}
+
+		if tok.text == "" {
+			if tok.column != "" {
+				return nil, fmt.Errorf("%w: %s: needs a value", ErrInvalidSearch, tok.column)
+			}
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+}
+
+func (p *searchParser) peekOperator(op string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	tok := p.tokens[p.pos]
+	return tok.kind == tokenWord && tok.column == "" && !tok.negate && !tok.prefix && tok.text == op
+}
+
+func (p *searchParser) parseOr() (searchNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []searchNode{first}
+	for p.peekOperator("OR") {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return searchOr{children: children}, nil
+}
+
+func (p *searchParser) parseAnd() (searchNode, error) {
+	var and searchAnd
+	for p.pos < len(p.tokens) {
+		if p.peekOperator("OR") || p.tokens[p.pos].kind == tokenClose {
+			break
+		}
+		if p.peekOperator("AND") {
+			p.pos++
+			continue
+		}
+		negate := false
+		if p.peekOperator("NOT") {
+			negate = true
+			p.pos++
+		}
+		node, negated, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if negate != negated {
+			and.exclude = append(and.exclude, node)
+		} else {
+			and.include = append(and.include, node)
+		}
+	}
+	if len(and.include) == 0 {
+		return nil, fmt.Errorf("%w: every group needs at least one term to include", ErrInvalidSearch)
+	}
+	if len(and.include) == 1 && len(and.exclude) == 0 {
+		return and.include[0], nil
+	}
+	return and, nil
+}
+
+// parseUnary parses a term or parenthesised group and reports whether it carried a "-" prefix.
+func (p *searchParser) parseUnary() (searchNode, bool, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, false, fmt.Errorf("%w: missing term", ErrInvalidSearch)
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, false, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenClose {
+			return nil, false, fmt.Errorf("%w: missing )", ErrInvalidSearch)
+		}
+		p.pos++
+		return node, false, nil
+	case tokenClose:
+		return nil, false, fmt.Errorf("%w: unexpected )", ErrInvalidSearch)
+	default:
+		return searchTerm{column: tok.column, text: tok.text, prefix: tok.prefix}, tok.negate, nil
+	}
+}
+
+// Search runs a full-text query (see parseSearchQuery for the syntax) and returns up to limit live
+// quotes in channel ordered by bm25 relevance, best first, together with the total number of
+// matches. A limit of zero or less returns every match.
+func (s *QuoteStore) Search(ctx context.Context, channel string, query string, limit int) ([]SearchResult, int, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, ErrNoQuotes
+	}
+	node, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	match := node.fts()
+	channel = normalizeChannel(channel)
+	if limit <= 0 {
+		limit = -1
+	}
+
+	var total int
+	const countQuery = `SELECT COUNT(*) FROM quotes_fts JOIN quotes ON quotes.id = quotes_fts.rowid
+                WHERE quotes_fts MATCH ? AND ` + liveInChannel
+	if err := s.read.QueryRowContext(ctx, countQuery, match, channel).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting matches: %w", err)
+	}
+	if total == 0 {
+		return nil, 0, ErrNoQuotes
+	}
+
+	// The text column is weighted above the author so "nick" ranks quotes mentioning nick
+	// ahead of every quote merely said by someone called nick.
+	const searchQuery = `SELECT ` + quoteColumns + `, bm25(quotes_fts, 1.0, 0.5) AS rank,
+                snippet(quotes_fts, 0, '*', '*', '...', 12)
+                FROM quotes_fts JOIN quotes ON quotes.id = quotes_fts.rowid
+                WHERE quotes_fts MATCH ? AND ` + liveInChannel + `
+                ORDER BY rank, quotes.channel_seq LIMIT ?`
+	rows, err := s.read.QueryContext(ctx, searchQuery, match, channel, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var res SearchResult
+		q, err := scanQuote(searchRowScanner{rows: rows, rank: &res.Rank, snippet: &res.Snippet})
+		if err != nil {
+			return nil, 0, fmt.Errorf("scanning quote: %w", err)
+		}
+		res.Quote = q
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating quotes: %w", err)
+	}
+	return results, total, nil
+}
+
+// searchRowScanner lets scanQuote read the quote columns of a search row while the trailing
+// rank and snippet columns are scanned into the result.
+type searchRowScanner struct {
+	rows    rowScanner
+	rank    *float64
+	snippet *string
+}
+
+func (s searchRowScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.rank, s.snippet)...)
+}
@@ -5,22 +5,313 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type AppConfig struct {
-	Mode          string `json:"mode"`
-	DBPath        string `json:"db_path"`
-	TwitchUser    string `json:"twitch_user"`
-	TwitchOAuth   string `json:"twitch_oauth"`
+	Mode   string `json:"mode"`
+	DBPath string `json:"db_path"`
+	// Store selects the storage backend: "sqlite" (default), "memory" or "jsonl". DBPath may
+	// instead carry the backend as a scheme, e.g. "jsonl:quotes.jsonl".
+	Store       string `json:"store,omitempty"`
+	TwitchUser  string `json:"twitch_user"`
+	TwitchOAuth string `json:"twitch_oauth"`
+	// TwitchChannel is the channel to join, or a comma-separated list of channels. Each channel
+	// keeps its own quotes and numbering in the shared database.
 	TwitchChannel string `json:"twitch_channel"`
+	// TwitchClientID enables Helix lookups of the stream category and title captured with new quotes.
+	// It must belong to the application that issued TwitchOAuth.
+	TwitchClientID string `json:"twitch_client_id,omitempty"`
+	// TrashRetentionDays is how long deleted quotes stay restorable before being purged.
+	// Zero uses the default of 30 days; a negative value keeps deleted quotes forever.
+	TrashRetentionDays int `json:"trash_retention_days,omitempty"`
+	// RandomWeight biases random picks within each shuffle-bag cycle: "none" (default) or "age".
+	RandomWeight string `json:"random_weight,omitempty"`
+	// FreshDays is the window for "!quote random fresh"; zero uses the default of 7 days.
+	FreshDays int `json:"fresh_days,omitempty"`
+	// BackupDir is where SQLite snapshots are written; empty uses a "backups" directory next to the database.
+	BackupDir string `json:"backup_dir,omitempty"`
+	// BackupIntervalHours is the time between scheduled snapshots. Zero uses the default of 24 hours;
+	// a negative value turns scheduled backups off.
+	BackupIntervalHours int `json:"backup_interval_hours,omitempty"`
+	// BackupKeep is how many snapshots are kept; zero uses the default of 7.
+	BackupKeep int `json:"backup_keep,omitempty"`
+	// SQLiteJournalMode is "wal" (default) or "delete", for file systems without shared memory.
+	SQLiteJournalMode string `json:"sqlite_journal_mode,omitempty"`
+	// SQLiteBusyTimeoutMS is how long to wait for another process's lock; zero uses 5000.
+	SQLiteBusyTimeoutMS int `json:"sqlite_busy_timeout_ms,omitempty"`
+	// SQLiteReadConns is the size of the read-only connection pool; zero uses 4.
+	SQLiteReadConns int `json:"sqlite_read_conns,omitempty"`
+	// SQLiteBusyRetries is how often a write still locked out after the timeout is retried. Zero
+	// uses the default of 3; a negative value turns retries off.
+	SQLiteBusyRetries int `json:"sqlite_busy_retries,omitempty"`
+	// SQLiteRetryDelayMS is the wait before the first retry, doubling each time; zero uses 50.
+	SQLiteRetryDelayMS int `json:"sqlite_retry_delay_ms,omitempty"`
+	// SQLiteChangelogPollMS is how often live updates check for changes made by other processes;
+	// zero uses 1000 and a negative value only reports changes made by this process.
+	SQLiteChangelogPollMS int `json:"sqlite_changelog_poll_ms,omitempty"`
+	// Timezone is the IANA time zone (e.g. "Europe/Berlin") dates are shown in; empty uses the
+	// system's local zone.
+	Timezone string `json:"timezone,omitempty"`
+	// DateFormat is how dates are shown: "iso" (default), "us", "eu", "date" or a Go layout.
+	DateFormat string `json:"date_format,omitempty"`
+	// Approval decides whose submissions wait for a moderator: "off" (default), "non-mods",
+	// "non-vips" or "non-subs".
+	Approval string `json:"approval,omitempty"`
+	// CommandPrefix is the chat command that starts every quote command; empty uses "!quote".
+	// CommandAliases are further root commands, e.g. "!q".
+	CommandPrefix  string   `json:"command_prefix,omitempty"`
+	CommandAliases []string `json:"command_aliases,omitempty"`
+	// SubcommandAliases maps extra words to the subcommand they run, e.g. {"hinzufuegen": "add"}.
+	SubcommandAliases map[string]string `json:"subcommand_aliases,omitempty"`
+	// CommandShortcuts maps standalone commands to the subcommand (and any leading arguments) they
+	// stand for, e.g. {"!addquote": "add"}.
+	CommandShortcuts map[string]string `json:"command_shortcuts,omitempty"`
+	// DisabledCommands lists subcommands that do not run.
+	DisabledCommands []string `json:"disabled_commands,omitempty"`
+	// CommandRoles sets the minimum role for subcommands, e.g. {"add": "vip", "upvote": "subscriber"}.
+	CommandRoles map[string]string `json:"command_roles,omitempty"`
+	// AllowedUsers and DeniedUsers list logins that may always or may never run a subcommand,
+	// keyed by subcommand or "*" for all of them.
+	AllowedUsers map[string][]string `json:"allowed_users,omitempty"`
+	DeniedUsers  map[string][]string `json:"denied_users,omitempty"`
+	// FollowerMinDays is how long a viewer must have followed to count as a follower.
+	FollowerMinDays int `json:"follower_min_days,omitempty"`
+	// CommandCooldowns limits how often subcommands run, keyed by subcommand or "*" for the rest.
+	CommandCooldowns map[string]CooldownConfig `json:"command_cooldowns,omitempty"`
+	// CooldownReply is "silent" (default) to drop commands that are cooling down or "wait" to say
+	// how long is left.
+	CooldownReply string `json:"cooldown_reply,omitempty"`
+	// CooldownExemptRole is the lowest role that skips cooldowns; empty uses "moderator".
+	CooldownExemptRole string `json:"cooldown_exempt_role,omitempty"`
+	// ResponseTemplates replaces chat replies, keyed by reply name, with Go text/template templates,
+	// e.g. {"quote": "#{{.id}} {{.text}} ({{.author}}, {{.date}})"}.
+	ResponseTemplates map[string]string `json:"response_templates,omitempty"`
+	// CooldownFile keeps cooldowns across restarts; empty keeps them in memory only.
+	CooldownFile string `json:"cooldown_file,omitempty"`
+	// PerChannel overrides settings for individual channels, keyed by channel name.
+	PerChannel map[string]ChannelConfig `json:"channel_settings,omitempty"`
+}
+
+// ChannelConfig holds the settings a channel may override; empty fields use the global value.
+type ChannelConfig struct {
+	Timezone           string                    `json:"timezone,omitempty"`
+	DateFormat         string                    `json:"date_format,omitempty"`
+	Approval           string                    `json:"approval,omitempty"`
+	CommandPrefix      string                    `json:"command_prefix,omitempty"`
+	CommandAliases     []string                  `json:"command_aliases,omitempty"`
+	SubcommandAliases  map[string]string         `json:"subcommand_aliases,omitempty"`
+	CommandShortcuts   map[string]string         `json:"command_shortcuts,omitempty"`
+	DisabledCommands   []string                  `json:"disabled_commands,omitempty"`
+	CommandRoles       map[string]string         `json:"command_roles,omitempty"`
+	AllowedUsers       map[string][]string       `json:"allowed_users,omitempty"`
+	DeniedUsers        map[string][]string       `json:"denied_users,omitempty"`
+	FollowerMinDays    int                       `json:"follower_min_days,omitempty"`
+	CommandCooldowns   map[string]CooldownConfig `json:"command_cooldowns,omitempty"`
+	CooldownReply      string                    `json:"cooldown_reply,omitempty"`
+	CooldownExemptRole string                    `json:"cooldown_exempt_role,omitempty"`
+	ResponseTemplates  map[string]string         `json:"response_templates,omitempty"`
+}
+
+// ChannelSettings is the resolved configuration the command handler applies in one channel.
+type ChannelSettings struct {
+	Display     TimeDisplay
+	Approval    ApprovalPolicy
+	Triggers    CommandTriggers
+	Permissions CommandPermissions
+	Cooldowns   CommandCooldowns
+	Templates   ResponseTemplates
+}
+
+// SettingsByChannel holds the resolved settings of every configured channel.
+type SettingsByChannel struct {
+	Default  ChannelSettings
+	Channels map[string]ChannelSettings
+}
+
+// For returns the settings for channel, falling back to the defaults.
+func (s SettingsByChannel) For(channel string) ChannelSettings {
+	if settings, ok := s.Channels[normalizeChannel(channel)]; ok {
+		return settings
+	}
+	return s.Default
+}
+
+// Display returns the time display of every channel.
+func (s SettingsByChannel) Display() DisplaySettings {
+	display := DisplaySettings{Default: s.Default.Display, Channels: make(map[string]TimeDisplay, len(s.Channels))}
+	for name, settings := range s.Channels {
+		display.Channels[name] = settings.Display
+	}
+	return display
 }
 
 const configFileName = "go-quote.config.json"
 
+// Channels returns the configured channels, normalized and de-duplicated, in the order given.
+func (c AppConfig) Channels() []string {
+	seen := make(map[string]bool)
+	var channels []string
+	for _, raw := range strings.Split(c.TwitchChannel, ",") {
+		channel := normalizeChannel(raw)
+		if channel == "" || seen[channel] {
+			continue
+		}
+		seen[channel] = true
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// RandomSettings validates the random selection options and applies their defaults.
+func (c AppConfig) RandomSettings() (RandomSettings, error) {
+	weight, err := parseRandomWeight(c.RandomWeight)
+	if err != nil {
+		return RandomSettings{}, err
+	}
+	days := c.FreshDays
+	if days <= 0 {
+		days = defaultFreshDays
+	}
+	return RandomSettings{Weight: weight, FreshWithin: time.Duration(days) * 24 * time.Hour}, nil
+}
+
+// BackupSettings resolves the backup options and applies their defaults.
+func (c AppConfig) BackupSettings() BackupSettings {
+	settings := BackupSettings{Dir: c.BackupDir, Keep: c.BackupKeep}
+	if settings.Dir == "" {
+		path := c.DBPath
+		if _, resolved, err := parseStoreLocation(c.Store, c.DBPath); err == nil {
+			path = resolved
+		}
+		settings.Dir = filepath.Join(filepath.Dir(path), backupDirName)
+	}
+	if settings.Keep <= 0 {
+		settings.Keep = defaultBackupKeep
+	}
+	switch hours := c.BackupIntervalHours; {
+	case hours == 0:
+		settings.Interval = defaultBackupIntervalHours * time.Hour
+	case hours > 0:
+		settings.Interval = time.Duration(hours) * time.Hour
+	}
+	return settings
+}
+
+// SQLiteSettings returns the SQLite connection options; NewQuoteStore validates them and applies
+// the defaults.
+func (c AppConfig) SQLiteSettings() SQLiteOptions {
+	return SQLiteOptions{
+		JournalMode:   c.SQLiteJournalMode,
+		BusyTimeout:   time.Duration(c.SQLiteBusyTimeoutMS) * time.Millisecond,
+		ReadConns:     c.SQLiteReadConns,
+		BusyRetries:   c.SQLiteBusyRetries,
+		RetryDelay:    time.Duration(c.SQLiteRetryDelayMS) * time.Millisecond,
+		ChangelogPoll: time.Duration(c.SQLiteChangelogPollMS) * time.Millisecond,
+	}
+}
+
+// ChannelSettings validates the global and per-channel settings and resolves each channel's
+// overrides on top of the global values. Command triggers are checked against the subcommands
+// registered so far.
+func (c AppConfig) ChannelSettings() (SettingsByChannel, error) {
+	registry, err := defaultRegistry()
+	if err != nil {
+		return SettingsByChannel{}, err
+	}
+	def, err := resolveChannelConfig(ChannelConfig{}, c, registry)
+	if err != nil {
+		return SettingsByChannel{}, err
+	}
+	settings := SettingsByChannel{Default: def, Channels: make(map[string]ChannelSettings)}
+	for name, cc := range c.PerChannel {
+		resolved, err := resolveChannelConfig(cc, c, registry)
+		if err != nil {
+			return SettingsByChannel{}, fmt.Errorf("channel %s: %w", name, err)
+		}
+		settings.Channels[normalizeChannel(name)] = resolved
+	}
+	return settings, nil
+}
+
+// resolveChannelConfig applies cc on top of the global values in c. A list or map set for the
+// channel replaces the global one rather than adding to it, except response templates, which
+// replace the global ones reply by reply.
+func resolveChannelConfig(cc ChannelConfig, c AppConfig, registry *CommandRegistry) (ChannelSettings, error) {
+	display, err := parseTimeDisplay(firstNonEmpty(cc.Timezone, c.Timezone), firstNonEmpty(cc.DateFormat, c.DateFormat))
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	approval, err := parseApprovalPolicy(firstNonEmpty(cc.Approval, c.Approval))
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	triggers, err := parseCommandTriggers(
+		firstNonEmpty(cc.CommandPrefix, c.CommandPrefix),
+		firstWithEntries(cc.CommandAliases, c.CommandAliases),
+		firstWithEntries(cc.SubcommandAliases, c.SubcommandAliases),
+		firstWithEntries(cc.CommandShortcuts, c.CommandShortcuts),
+		firstWithEntries(cc.DisabledCommands, c.DisabledCommands),
+		registry)
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	followerMinDays := cc.FollowerMinDays
+	if followerMinDays == 0 {
+		followerMinDays = c.FollowerMinDays
+	}
+	permissions, err := parseCommandPermissions(
+		firstWithEntries(cc.CommandRoles, c.CommandRoles),
+		firstWithEntries(cc.AllowedUsers, c.AllowedUsers),
+		firstWithEntries(cc.DeniedUsers, c.DeniedUsers),
+		followerMinDays,
+		registry)
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	cooldowns, err := parseCommandCooldowns(
+		firstWithEntries(cc.CommandCooldowns, c.CommandCooldowns),
+		firstNonEmpty(cc.CooldownReply, c.CooldownReply),
+		firstNonEmpty(cc.CooldownExemptRole, c.CooldownExemptRole),
+		registry)
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	templates := make(map[string]string, len(c.ResponseTemplates)+len(cc.ResponseTemplates))
+	for name, text := range c.ResponseTemplates {
+		templates[name] = text
+	}
+	for name, text := range cc.ResponseTemplates {
+		templates[name] = text
+	}
+	responseTemplates, err := parseResponseTemplates(templates)
+	if err != nil {
+		return ChannelSettings{}, err
+	}
+	return ChannelSettings{
+		Display:     display,
+		Approval:    approval,
+		Triggers:    triggers,
+		Permissions: permissions,
+		Cooldowns:   cooldowns,
+		Templates:   responseTemplates,
+	}, nil
+}
+
+// PrimaryChannel returns the first configured channel, which the CLI and TUI work on by default
+// and which inherits quotes created before quotes were scoped to channels.
+func (c AppConfig) PrimaryChannel() string {
+	if channels := c.Channels(); len(channels) > 0 {
+		return channels[0]
+	}
+	return ""
+}
+
 // setup merges defaults, persisted config, environment overrides (via applyEnvDefaults), and CLI flags,
 // then writes the resolved configuration back to disk so users only enter credentials once.
-func setup(mode, dbPath, user, oauth, channel string) (AppConfig, error) {
+func setup(mode, dbPath, store, user, oauth, channel string) (AppConfig, error) {
 	defaults := AppConfig{
 		Mode:   "twitch",
 		DBPath: "quotes.db",
@@ -33,15 +324,21 @@ func setup(mode, dbPath, user, oauth, channel string) (AppConfig, error) {
 		return AppConfig{}, fmt.Errorf("reading config file: %w", err)
 	}
 
+	envCfg := AppConfig{
+		Store:          os.Getenv("GOQUOTE_STORE"),
+		TwitchClientID: firstNonEmpty(os.Getenv("GOQUOTE_CLIENT_ID"), os.Getenv("TWITCH_CLIENT_ID")),
+	}
+
 	flagCfg := AppConfig{
 		Mode:          strings.TrimSpace(mode),
 		DBPath:        strings.TrimSpace(dbPath),
+		Store:         strings.TrimSpace(store),
 		TwitchUser:    strings.TrimSpace(user),
 		TwitchOAuth:   strings.TrimSpace(oauth),
 		TwitchChannel: strings.TrimSpace(channel),
 	}
 
-	finalCfg := mergeConfigs(defaults, fileCfg, flagCfg)
+	finalCfg := mergeConfigs(defaults, fileCfg, envCfg, flagCfg)
 	finalCfg.Mode = strings.ToLower(finalCfg.Mode)
 
 	if err := saveConfigFile(configFileName, finalCfg); err != nil {
@@ -60,6 +357,9 @@ func mergeConfigs(configs ...AppConfig) AppConfig {
 		if cfg.DBPath != "" {
 			merged.DBPath = cfg.DBPath
 		}
+		if cfg.Store != "" {
+			merged.Store = cfg.Store
+		}
 		if cfg.TwitchUser != "" {
 			merged.TwitchUser = cfg.TwitchUser
 		}
@@ -69,6 +369,99 @@ func mergeConfigs(configs ...AppConfig) AppConfig {
 		if cfg.TwitchChannel != "" {
 			merged.TwitchChannel = cfg.TwitchChannel
 		}
+		if cfg.TwitchClientID != "" {
+			merged.TwitchClientID = cfg.TwitchClientID
+		}
+		if cfg.TrashRetentionDays != 0 {
+			merged.TrashRetentionDays = cfg.TrashRetentionDays
+		}
+		if cfg.RandomWeight != "" {
+			merged.RandomWeight = cfg.RandomWeight
+		}
+		if cfg.FreshDays != 0 {
+			merged.FreshDays = cfg.FreshDays
+		}
+		if cfg.BackupDir != "" {
+			merged.BackupDir = cfg.BackupDir
+		}
+		if cfg.BackupIntervalHours != 0 {
+			merged.BackupIntervalHours = cfg.BackupIntervalHours
+		}
+		if cfg.BackupKeep != 0 {
+			merged.BackupKeep = cfg.BackupKeep
+		}
+		if cfg.SQLiteJournalMode != "" {
+			merged.SQLiteJournalMode = cfg.SQLiteJournalMode
+		}
+		if cfg.SQLiteBusyTimeoutMS != 0 {
+			merged.SQLiteBusyTimeoutMS = cfg.SQLiteBusyTimeoutMS
+		}
+		if cfg.SQLiteReadConns != 0 {
+			merged.SQLiteReadConns = cfg.SQLiteReadConns
+		}
+		if cfg.SQLiteBusyRetries != 0 {
+			merged.SQLiteBusyRetries = cfg.SQLiteBusyRetries
+		}
+		if cfg.SQLiteRetryDelayMS != 0 {
+			merged.SQLiteRetryDelayMS = cfg.SQLiteRetryDelayMS
+		}
+		if cfg.SQLiteChangelogPollMS != 0 {
+			merged.SQLiteChangelogPollMS = cfg.SQLiteChangelogPollMS
+		}
+		if cfg.Timezone != "" {
+			merged.Timezone = cfg.Timezone
+		}
+		if cfg.DateFormat != "" {
+			merged.DateFormat = cfg.DateFormat
+		}
+		if cfg.Approval != "" {
+			merged.Approval = cfg.Approval
+		}
+		if cfg.CommandPrefix != "" {
+			merged.CommandPrefix = cfg.CommandPrefix
+		}
+		if cfg.CommandAliases != nil {
+			merged.CommandAliases = cfg.CommandAliases
+		}
+		if cfg.SubcommandAliases != nil {
+			merged.SubcommandAliases = cfg.SubcommandAliases
+		}
+		if cfg.CommandShortcuts != nil {
+			merged.CommandShortcuts = cfg.CommandShortcuts
+		}
+		if cfg.DisabledCommands != nil {
+			merged.DisabledCommands = cfg.DisabledCommands
+		}
+		if cfg.CommandRoles != nil {
+			merged.CommandRoles = cfg.CommandRoles
+		}
+		if cfg.AllowedUsers != nil {
+			merged.AllowedUsers = cfg.AllowedUsers
+		}
+		if cfg.DeniedUsers != nil {
+			merged.DeniedUsers = cfg.DeniedUsers
+		}
+		if cfg.FollowerMinDays != 0 {
+			merged.FollowerMinDays = cfg.FollowerMinDays
+		}
+		if cfg.CommandCooldowns != nil {
+			merged.CommandCooldowns = cfg.CommandCooldowns
+		}
+		if cfg.CooldownReply != "" {
+			merged.CooldownReply = cfg.CooldownReply
+		}
+		if cfg.CooldownExemptRole != "" {
+			merged.CooldownExemptRole = cfg.CooldownExemptRole
+		}
+		if cfg.ResponseTemplates != nil {
+			merged.ResponseTemplates = cfg.ResponseTemplates
+		}
+		if cfg.CooldownFile != "" {
+			merged.CooldownFile = cfg.CooldownFile
+		}
+		if cfg.PerChannel != nil {
+			merged.PerChannel = cfg.PerChannel
+		}
 	}
 	return merged
 }
@@ -97,14 +490,7 @@ func saveConfigFile(path string, cfg AppConfig) error {
 // can set credentials once instead of passing them on every run. It trims whitespace and
 // normalizes the mode to lower-case, defaulting to "twitch" when unset.
 func applyEnvDefaults(mode, dbPath, user, oauth, channel *string) {
-	pick := func(values ...string) string {
-		for _, v := range values {
-			if trimmed := strings.TrimSpace(v); trimmed != "" {
-				return trimmed
-			}
-		}
-		return ""
-	}
+	pick := firstNonEmpty
 
 	if mode != nil {
 		*mode = pick(*mode, os.Getenv("GOQUOTE_MODE"))
@@ -130,3 +516,23 @@ func applyEnvDefaults(mode, dbPath, user, oauth, channel *string) {
 		*channel = pick(*channel, os.Getenv("GOQUOTE_CHANNEL"), os.Getenv("TWITCH_CHANNEL"))
 	}
 }
+
+// firstNonEmpty returns the first value that is not blank after trimming whitespace.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// firstWithEntries returns the first of values that holds any entries.
+func firstWithEntries[T []string | map[string]string | map[string][]string | map[string]CooldownConfig](values ...T) T {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLite connection defaults used when SQLiteOptions leaves them unset.
+const (
+	journalModeWAL     = "wal"
+	journalModeDelete  = "delete"
+	defaultBusyTimeout = 5 * time.Second
+	defaultReadConns   = 4
+	defaultBusyRetries = 3
+	defaultRetryDelay  = 50 * time.Millisecond
+	defaultChangePoll  = time.Second
+)
+
+// SQLiteOptions controls how a QuoteStore shares its database file with other processes, such as
+// the TUI polling the database while the bot writes to it. The zero value uses the defaults.
+type SQLiteOptions struct {
+	// JournalMode is "wal" (default), which lets readers carry on while another connection
+	// writes, or "delete", SQLite's rollback journal, for file systems without shared memory
+	// support such as network shares.
+	JournalMode string
+	// BusyTimeout is how long a connection waits for another one's lock before giving up with
+	// SQLITE_BUSY.
+	BusyTimeout time.Duration
+	// ReadConns is the size of the read-only connection pool queries use. Writes always go
+	// through a single connection.
+	ReadConns int
+	// BusyRetries is how many times a write that still fails with SQLITE_BUSY after BusyTimeout
+	// is retried, waiting RetryDelay and then twice as long each time. Negative disables retries.
+	BusyRetries int
+	RetryDelay  time.Duration
+	// ChangelogPoll is how often subscriptions check the changelog for changes made by other
+	// processes; changes made through the store itself are delivered at once. Negative disables
+	// polling.
+	ChangelogPoll time.Duration
+}
+
+// withDefaults validates the options and fills in the defaults.
+func (o SQLiteOptions) withDefaults() (SQLiteOptions, error) {
+	switch o.JournalMode = strings.ToLower(strings.TrimSpace(o.JournalMode)); o.JournalMode {
+	case "":
+		o.JournalMode = journalModeWAL
+	case journalModeWAL, journalModeDelete:
+	default:
+		return SQLiteOptions{}, fmt.Errorf("unknown journal mode %q (use wal or delete)", o.JournalMode)
+	}
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = defaultBusyTimeout
+	}
+	if o.ReadConns <= 0 {
+		o.ReadConns = defaultReadConns
+	}
+	switch {
+	case o.BusyRetries == 0:
+		o.BusyRetries = defaultBusyRetries
+	case o.BusyRetries < 0:
+		o.BusyRetries = 0
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = defaultRetryDelay
+	}
+	if o.ChangelogPoll == 0 {
+		o.ChangelogPoll = defaultChangePoll
+	}
+	return o, nil
+}
+
+// isMemoryDatabase reports whether dbPath names an in-memory database, which exists only within
+// one connection and so cannot be split into reader and writer pools.
+func isMemoryDatabase(dbPath string) bool {
+	return dbPath == ":memory:" || strings.Contains(dbPath, "mode=memory") || strings.HasPrefix(dbPath, "file::memory:")
+}
+
+// writerDSN returns the data source name of the single writer connection. Its transactions take
+// the write lock as they begin (BEGIN IMMEDIATE), so a writer waits for another process's lock
+// within the busy timeout instead of failing when a read turns into a write.
+func (o SQLiteOptions) writerDSN(dbPath string) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", o.BusyTimeout.Milliseconds()))
+	if !isMemoryDatabase(dbPath) {
+		params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", o.JournalMode))
+	}
+	params.Set("_txlock", "immediate")
+	return withQueryParams(dbPath, params)
+}
+
+// readerDSN returns the data source name of the read-only connection pool.
+func (o SQLiteOptions) readerDSN(dbPath string) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", o.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "query_only(1)")
+	return withQueryParams(dbPath, params)
+}
+
+// withQueryParams appends driver parameters to dbPath, which may already carry some.
+func withQueryParams(dbPath string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + params.Encode()
+}
+
+// isBusy reports whether err is SQLite failing to get a lock held by another connection.
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// retryBusy runs fn, running it again with exponential backoff while it fails with SQLITE_BUSY,
+// up to the store's configured number of retries. fn must be safe to repeat.
+func (s *QuoteStore) retryBusy(ctx context.Context, fn func() error) error {
+	delay := s.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isBusy(err) || attempt >= s.options.BusyRetries {
+			return err
+		}
+		// Jitter keeps two processes that collided from retrying in lockstep.
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// statsMonthLayout formats the month keys of QuoteStats.AddedPerMonth.
+const statsMonthLayout = "2006-01"
+
+// QuoteStats summarises a channel's live quotes and how often chat has been shown them.
+type QuoteStats struct {
+	Total   int
+	Authors int
+	// Views is the sum of every quote's view count; NeverShown counts quotes with no views.
+	Views      int
+	NeverShown int
+	// LastShown is the quote shown most recently, or nil when none has been shown.
+	LastShown *Quote
+	// TopAuthors and MostShown are ordered by count, highest first.
+	TopAuthors []AuthorCount
+	MostShown  []Quote
+	// AddedPerMonth lists the months (in UTC) in which quotes were added, oldest first.
+	AddedPerMonth []MonthCount
+}
+
+// AuthorCount is how many quotes an author has said.
+type AuthorCount struct {
+	Name  string
+	Count int
+}
+
+// MonthCount is how many quotes were added in a month; Month is the first day of the month in UTC.
+type MonthCount struct {
+	Month time.Time
+	Count int
+}
+
+// RecordView counts one view of the live quote numbered id in channel and stamps it as shown now.
+func (s *QuoteStore) RecordView(ctx context.Context, channel string, id int) error {
+	var res sql.Result
+	err := s.retryBusy(ctx, func() (err error) {
+		res, err = s.db.ExecContext(ctx, "UPDATE quotes SET view_count = view_count + 1, last_shown_at = ? WHERE "+liveInChannel+" AND channel_seq = ?",
+			formatSQLiteTime(time.Now()), normalizeChannel(channel), id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("recording view: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no quote with id %d found", id)
+	}
+	return nil
+}
+
+// Stats summarises the live quotes in channel, listing up to limit top authors and most-shown
+// quotes (all of them when limit is not positive). It returns ErrNoQuotes when the channel is empty.
+func (s *QuoteStore) Stats(ctx context.Context, channel string, limit int) (QuoteStats, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	channel = normalizeChannel(channel)
+	var stats QuoteStats
+	err := s.read.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT author COLLATE NOCASE),
+                COALESCE(SUM(view_count), 0), COALESCE(SUM(view_count = 0), 0)
+                FROM quotes WHERE `+liveInChannel, channel).Scan(&stats.Total, &stats.Authors, &stats.Views, &stats.NeverShown)
+	if err != nil {
+		return QuoteStats{}, fmt.Errorf("counting quotes: %w", err)
+	}
+	if stats.Total == 0 {
+		return QuoteStats{}, ErrNoQuotes
+	}
+
+	last, err := scanQuote(s.read.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" AND quotes.last_shown_at IS NOT NULL ORDER BY quotes.last_shown_at DESC, quotes.channel_seq LIMIT 1", channel))
+	switch {
+	case err == nil:
+		stats.LastShown = &last
+	case !errors.Is(err, sql.ErrNoRows):
+		return QuoteStats{}, fmt.Errorf("fetching last shown quote: %w", err)
+	}
+
+	rows, err := s.read.QueryContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" AND quotes.view_count > 0 ORDER BY quotes.view_count DESC, quotes.channel_seq LIMIT ?", channel, limit)
+	if err != nil {
+		return QuoteStats{}, fmt.Errorf("ranking shown quotes: %w", err)
+	}
+	if stats.MostShown, err = scanQuotes(rows); err != nil && !errors.Is(err, ErrNoQuotes) {
+		return QuoteStats{}, err
+	}
+
+	if stats.TopAuthors, err = s.topAuthors(ctx, channel, limit); err != nil {
+		return QuoteStats{}, err
+	}
+	if stats.AddedPerMonth, err = s.addedPerMonth(ctx, channel); err != nil {
+		return QuoteStats{}, err
+	}
+	return stats, nil
+}
+
+// topAuthors returns up to limit authors (limit -1 for all) ordered by how many live quotes in
+// channel they said. Authors differing only in case are counted together.
+func (s *QuoteStore) topAuthors(ctx context.Context, channel string, limit int) ([]AuthorCount, error) {
+	const query = `SELECT MIN(author), COUNT(*) AS said FROM quotes WHERE ` + liveInChannel + `
+                GROUP BY author COLLATE NOCASE ORDER BY said DESC, MIN(author) COLLATE NOCASE LIMIT ?`
+	rows, err := s.read.QueryContext(ctx, query, channel, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ranking authors: %w", err)
+	}
+	defer rows.Close()
+
+	var authors []AuthorCount
+	for rows.Next() {
+		var ac AuthorCount
+		if err := rows.Scan(&ac.Name, &ac.Count); err != nil {
+			return nil, fmt.Errorf("scanning author: %w", err)
+		}
+		authors = append(authors, ac)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating authors: %w", err)
+	}
+	return authors, nil
+}
+
+// addedPerMonth counts the live quotes in channel by the month they were added. Timestamps are
+// stored in UTC with the date first, so the month is the first seven characters.
+func (s *QuoteStore) addedPerMonth(ctx context.Context, channel string) ([]MonthCount, error) {
+	const query = `SELECT substr(created_at, 1, 7) AS month, COUNT(*) FROM quotes WHERE ` + liveInChannel + `
+                GROUP BY month ORDER BY month`
+	rows, err := s.read.QueryContext(ctx, query, channel)
+	if err != nil {
+		return nil, fmt.Errorf("counting quotes per month: %w", err)
+	}
+	defer rows.Close()
+
+	var months []MonthCount
+	for rows.Next() {
+		var month string
+		var mc MonthCount
+		if err := rows.Scan(&month, &mc.Count); err != nil {
+			return nil, fmt.Errorf("scanning month: %w", err)
+		}
+		if mc.Month, err = time.Parse(statsMonthLayout, month); err != nil {
+			return nil, fmt.Errorf("unsupported timestamp format: %q", month)
+		}
+		months = append(months, mc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating months: %w", err)
+	}
+	return months, nil
+}
+
+// monthsBetween returns counts for every month from the first to the last in counts, filling the
+// months without additions with zero so reports show gaps.
+func monthsBetween(counts []MonthCount) []MonthCount {
+	if len(counts) == 0 {
+		return nil
+	}
+	var filled []MonthCount
+	next := 0
+	for month := counts[0].Month; !month.After(counts[len(counts)-1].Month); month = month.AddDate(0, 1, 0) {
+		mc := MonthCount{Month: month}
+		if next < len(counts) && counts[next].Month.Equal(month) {
+			mc.Count = counts[next].Count
+			next++
+		}
+		filled = append(filled, mc)
+	}
+	return filled
+}
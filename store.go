@@ -11,28 +11,87 @@ import (
 	"time"
 )
 
-// Quote holds the quote details.
+// Quote holds the quote details. ID is the human-friendly number within Channel (#1, #2, ...);
+// RowID is the database-wide primary key used internally. Game and StreamTitle capture what was on
+// stream when the quote was added. SubmittedBy, SubmitterID and Platform record who added the quote,
+// which may differ from Author; they are empty for quotes whose submitter was never recorded. Score
+// is the sum of the +1/-1 votes cast by viewers.
+// DeletedAt is zero for live quotes and set once a quote has been moved to the trash.
 type Quote struct {
-	ID        int
-	Text      string
-	Author    string
+	ID          int       `json:"id"`
+	RowID       int64     `json:"row_id"`
+	Channel     string    `json:"channel"`
+	Text        string    `json:"text"`
+	Author      string    `json:"author"`
+	Game        string    `json:"game,omitempty"`
+	StreamTitle string    `json:"stream_title,omitempty"`
+	SubmittedBy string    `json:"submitted_by,omitempty"`
+	SubmitterID string    `json:"submitter_id,omitempty"`
+	Platform    string    `json:"platform,omitempty"`
+	Score       int       `json:"score,omitempty"`
+	Views       int       `json:"views,omitempty"`
+	LastShownAt time.Time `json:"last_shown_at,omitzero"`
+	CreatedAt   time.Time `json:"created_at"`
+	DeletedAt   time.Time `json:"deleted_at,omitzero"`
+	DeletedBy   string    `json:"deleted_by,omitempty"`
+}
+
+// NewQuote holds everything needed to add a quote. Actor is the display name of who submitted it and
+// is recorded as the submitter and in the history; ActorID is their user ID on Platform, if it has one.
+type NewQuote struct {
+	Text        string
+	Author      string
+	Actor       string
+	ActorID     string
+	Platform    string
+	Tags        []string
+	Game        string
+	StreamTitle string
+	// CreatedAt is when the quote was originally added, for imports; zero means now.
 	CreatedAt time.Time
 }
 
+// quoteColumns is the column list understood by scanQuote. Columns are table-qualified so the
+// list can be used in joins.
+const quoteColumns = "quotes.id, quotes.channel, quotes.channel_seq, quotes.text, quotes.author, quotes.game, quotes.stream_title, quotes.submitted_by, quotes.submitter_id, quotes.platform, quotes.score, quotes.view_count, quotes.last_shown_at, quotes.created_at, quotes.deleted_at, quotes.deleted_by"
+
+// liveInChannel restricts a query on quotes to live quotes in one channel; it takes the channel as its argument.
+const liveInChannel = "quotes.channel = ? AND quotes.deleted_at IS NULL"
+
+// sqliteTimeLayout matches the format SQLite uses for CURRENT_TIMESTAMP so values written from Go
+// compare correctly against column defaults.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
 // ErrNoQuotes is returned when the database does not contain any quotes that
 // satisfy the requested operation.
 var ErrNoQuotes = errors.New("no quotes available")
 
-// QuoteStore provides database backed persistence for quotes.
+// QuoteStore provides database backed persistence for quotes. Writes go through db, a single
+// connection, and queries through read, a pool of read-only connections.
 type QuoteStore struct {
 	db       *sql.DB
+	read     *sql.DB
+	options  SQLiteOptions
 	random   *rand.Rand
 	randomMu sync.Mutex
+
+	// events carries changes to subscribers; changed wakes the changelog tail after a write, which
+	// runs once tailing is set (see Subscribe).
+	events  *changeHub
+	changed chan struct{}
+	tailMu  sync.Mutex
+	tailing bool
 }
 
-// Returns a non-nil error if the database cannot be opened, pinged, or initialized (table creation).
-func NewQuoteStore(ctx context.Context, dbPath string) (*QuoteStore, error) {
-	db, err := sql.Open("sqlite", dbPath)
+// NewQuoteStore opens the SQLite database at dbPath with opts and applies any pending schema
+// migrations. Returns a non-nil error if the database cannot be opened, pinged, or migrated,
+// including when the database was created by a newer binary (ErrSchemaTooNew).
+func NewQuoteStore(ctx context.Context, dbPath string, opts SQLiteOptions) (*QuoteStore, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", opts.writerDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("opening db: %w", err)
 	}
@@ -46,58 +105,78 @@ func NewQuoteStore(ctx context.Context, dbPath string) (*QuoteStore, error) {
 		return nil, fmt.Errorf("pinging db: %w", err)
 	}
 
-	// Create table if it doesn't exist.
-	const query = `CREATE TABLE IF NOT EXISTS quotes (
-                id INTEGER PRIMARY KEY AUTOINCREMENT,
-                text TEXT NOT NULL,
-                author TEXT NOT NULL,
-                created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-        );`
-	if _, err := db.ExecContext(ctx, query); err != nil {
+	if _, err := migrateUp(ctx, db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("creating table: %w", err)
+		return nil, fmt.Errorf("migrating schema: %w", err)
+	}
+
+	// An in-memory database only exists inside the writer's connection, so it serves reads too.
+	read := db
+	if !isMemoryDatabase(dbPath) {
+		if read, err = sql.Open("sqlite", opts.readerDSN(dbPath)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("opening read pool: %w", err)
+		}
+		read.SetMaxOpenConns(opts.ReadConns)
+		read.SetMaxIdleConns(opts.ReadConns)
 	}
 
 	return &QuoteStore{
-		db:     db,
-		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		db:      db,
+		read:    read,
+		options: opts,
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		events:  newChangeHub(),
+		changed: make(chan struct{}, 1),
 	}, nil
 }
 
-// Close releases database resources.
+// Close releases database resources and ends every subscription.
 func (s *QuoteStore) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
-	return s.db.Close()
+	s.events.close()
+	var readErr error
+	if s.read != nil && s.read != s.db {
+		readErr = s.read.Close()
+	}
+	return errors.Join(s.db.Close(), readErr)
 }
 
 type rowScanner interface {
 	Scan(dest ...any) error
 }
 
-// parseSQLiteTime parses a timestamp string using several known layouts in the local time zone.
+// formatSQLiteTime formats t in UTC using the same layout as SQLite's CURRENT_TIMESTAMP.
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// parseSQLiteTime parses a stored timestamp and returns it in UTC. Values without a zone, such as
+// those written by CURRENT_TIMESTAMP and formatSQLiteTime, are UTC.
 // Supported layouts are "2006-01-02 15:04:05", time.RFC3339Nano, and time.RFC3339; it returns the parsed time or an error if the format is unsupported.
 func parseSQLiteTime(value string) (time.Time, error) {
 	layouts := []string{
-		"2006-01-02 15:04:05",
+		sqliteTimeLayout,
 		time.RFC3339Nano,
 		time.RFC3339,
 	}
 	for _, layout := range layouts {
-		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
-			return t, nil
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UTC(), nil
 		}
 	}
 	return time.Time{}, fmt.Errorf("unsupported timestamp format: %q", value)
 }
 
-// scanQuote scans a rowScanner into a Quote and parses its created timestamp.
-// It returns the populated Quote on success or an error if scanning the row or parsing the creation time fails.
+// scanQuote scans a row selected with quoteColumns into a Quote and parses its timestamps.
+// It returns the populated Quote on success or an error if scanning the row or parsing a timestamp fails.
 func scanQuote(scanner rowScanner) (Quote, error) {
 	var q Quote
 	var created string
-	if err := scanner.Scan(&q.ID, &q.Text, &q.Author, &created); err != nil {
+	var lastShownAt, deletedAt, deletedBy sql.NullString
+	if err := scanner.Scan(&q.RowID, &q.Channel, &q.ID, &q.Text, &q.Author, &q.Game, &q.StreamTitle, &q.SubmittedBy, &q.SubmitterID, &q.Platform, &q.Score, &q.Views, &lastShownAt, &created, &deletedAt, &deletedBy); err != nil {
 		return Quote{}, err
 	}
 	parsedTime, err := parseSQLiteTime(created)
@@ -105,71 +184,22 @@ func scanQuote(scanner rowScanner) (Quote, error) {
 		return Quote{}, err
 	}
 	q.CreatedAt = parsedTime
-	return q, nil
-}
-
-// Add inserts a new quote into the database.
-func (s *QuoteStore) Add(ctx context.Context, text, author string) (int64, error) {
-	if s == nil {
-		return 0, errors.New("quote store is not initialized")
-	}
-	text = strings.TrimSpace(text)
-	author = strings.TrimSpace(author)
-
-	if text == "" {
-		return 0, fmt.Errorf("quote text cannot be empty")
-	}
-	if author == "" {
-		return 0, fmt.Errorf("author cannot be empty")
-	}
-
-	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO quotes(text, author) VALUES(?, ?)")
-	if err != nil {
-		return 0, fmt.Errorf("preparing statement: %w", err)
-	}
-	defer stmt.Close()
-
-	res, err := stmt.ExecContext(ctx, text, author)
-	if err != nil {
-		return 0, fmt.Errorf("executing insert: %w", err)
-	}
-	return res.LastInsertId()
-}
-
-// Random returns a random quote.
-func (s *QuoteStore) Random(ctx context.Context) (*Quote, error) {
-	var count int
-	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM quotes").Scan(&count); err != nil {
-		return nil, fmt.Errorf("counting quotes: %w", err)
-	}
-	if count == 0 {
-		return nil, ErrNoQuotes
+	if lastShownAt.Valid {
+		if q.LastShownAt, err = parseSQLiteTime(lastShownAt.String); err != nil {
+			return Quote{}, err
+		}
 	}
-
-	s.randomMu.Lock()
-	offset := s.random.Intn(count)
-	s.randomMu.Unlock()
-	row := s.db.QueryRowContext(ctx, "SELECT id, text, author, created_at FROM quotes ORDER BY id LIMIT 1 OFFSET ?", offset)
-	q, err := scanQuote(row)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNoQuotes
+	if deletedAt.Valid {
+		if q.DeletedAt, err = parseSQLiteTime(deletedAt.String); err != nil {
+			return Quote{}, err
 		}
-		return nil, fmt.Errorf("scanning random quote: %w", err)
 	}
-	return &q, nil
+	q.DeletedBy = deletedBy.String
+	return q, nil
 }
 
-// Search returns quotes that match the given search term.
-func (s *QuoteStore) Search(ctx context.Context, term string) ([]Quote, error) {
-	if term == "" {
-		return nil, ErrNoQuotes
-	}
-	likeTerm := "%" + term + "%"
-	rows, err := s.db.QueryContext(ctx, "SELECT id, text, author, created_at FROM quotes WHERE text LIKE ? OR author LIKE ?", likeTerm, likeTerm)
-	if err != nil {
-		return nil, fmt.Errorf("querying quotes: %w", err)
-	}
+// scanQuotes collects every row into a slice, returning ErrNoQuotes when there are none.
+func scanQuotes(rows *sql.Rows) ([]Quote, error) {
 	defer rows.Close()
 
 	var quotes []Quote
@@ -189,90 +219,240 @@ func (s *QuoteStore) Search(ctx context.Context, term string) ([]Quote, error) {
 	return quotes, nil
 }
 
-// List retrieves all quotes (ordered by ID).
-func (s *QuoteStore) List(ctx context.Context) ([]Quote, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, text, author, created_at FROM quotes ORDER BY id")
+// Add inserts a new quote with its tags and stream context into channel, records the addition in
+// the quote's history, and returns the quote's number within the channel. It fails with a
+// *DuplicateQuoteError when a live quote in the channel has the same fingerprint, and reports the
+// closest recent quote when the new one is a near-duplicate.
+func (s *QuoteStore) Add(ctx context.Context, channel string, nq NewQuote) (AddResult, error) {
+	if s == nil {
+		return AddResult{}, errors.New("quote store is not initialized")
+	}
+	if _, _, _, err := validateNewQuote(nq); err != nil {
+		return AddResult{}, err
+	}
+	var result AddResult
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		result, err = addTx(ctx, tx, channel, nq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("listing quotes: %w", err)
+		return AddResult{}, err
 	}
-	defer rows.Close()
+	return result, nil
+}
 
-	var quotes []Quote
-	for rows.Next() {
-		q, err := scanQuote(rows)
-		if err != nil {
-			return nil, fmt.Errorf("scanning quote: %w", err)
-		}
-		quotes = append(quotes, q)
+// addTx performs Add inside tx.
+func addTx(ctx context.Context, tx *sql.Tx, channel string, nq NewQuote) (AddResult, error) {
+	text, author, tags, err := validateNewQuote(nq)
+	if err != nil {
+		return AddResult{}, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating quotes: %w", err)
+	channel = normalizeChannel(channel)
+	fingerprint := quoteFingerprint(text)
+
+	if err := duplicateTx(ctx, tx, channel, fingerprint); err != nil {
+		return AddResult{}, err
 	}
-	if len(quotes) == 0 {
-		return nil, ErrNoQuotes
+	var result AddResult
+	if result.Similar, err = similarRecentTx(ctx, tx, channel, fingerprint); err != nil {
+		return AddResult{}, err
 	}
-	return quotes, nil
+	seq, err := nextChannelSeq(ctx, tx, channel)
+	if err != nil {
+		return AddResult{}, err
+	}
+	result.ID = seq
+	res, err := tx.ExecContext(ctx, insertQuoteQuery, insertQuoteArgs(channel, seq, text, author, fingerprint, nq)...)
+	if err != nil {
+		return AddResult{}, fmt.Errorf("executing insert: %w", err)
+	}
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return AddResult{}, err
+	}
+	if _, err := addTagsTx(ctx, tx, rowID, tags); err != nil {
+		return AddResult{}, err
+	}
+	err = recordRevision(ctx, tx, Revision{
+		QuoteID:    seq,
+		Channel:    channel,
+		quoteRowID: rowID,
+		Actor:      nq.Actor,
+		Action:     RevisionAdd,
+		NewText:    text,
+		NewAuthor:  author,
+		Note:       formatTags(tags),
+	})
+	if err != nil {
+		return AddResult{}, err
+	}
+	return result, nil
 }
 
-// Delete removes a quote with the given ID.
-func (s *QuoteStore) Delete(ctx context.Context, id int) error {
-	res, err := s.db.ExecContext(ctx, "DELETE FROM quotes WHERE id = ?", id)
-	if err != nil {
-		return fmt.Errorf("deleting quote: %w", err)
+// insertQuoteQuery inserts a validated new quote; insertQuoteArgs supplies its arguments.
+const insertQuoteQuery = `INSERT INTO quotes(channel, channel_seq, text, author, game, stream_title, submitted_by, submitter_id, platform, fingerprint, created_at)
+                VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// insertQuoteArgs returns the arguments of insertQuoteQuery for nq, numbered seq in channel, with
+// its already validated text and author.
+func insertQuoteArgs(channel string, seq int, text, author, fingerprint string, nq NewQuote) []any {
+	return []any{channel, seq, text, author, strings.TrimSpace(nq.Game), strings.TrimSpace(nq.StreamTitle),
+		strings.TrimSpace(nq.Actor), strings.TrimSpace(nq.ActorID), strings.TrimSpace(nq.Platform), fingerprint, formatSQLiteTime(nq.createdAt())}
+}
+
+// createdAt returns when nq was added: its CreatedAt when set, otherwise now.
+func (nq NewQuote) createdAt() time.Time {
+	if nq.CreatedAt.IsZero() {
+		return time.Now()
+	}
+	return nq.CreatedAt
+}
+
+// validateNewQuote trims a new quote's text and author and normalizes its tags, rejecting
+// empty text or author and invalid tags.
+func validateNewQuote(nq NewQuote) (text, author string, tags []string, err error) {
+	text = strings.TrimSpace(nq.Text)
+	author = strings.TrimSpace(nq.Author)
+	if text == "" {
+		return "", "", nil, fmt.Errorf("quote text cannot be empty")
+	}
+	if author == "" {
+		return "", "", nil, fmt.Errorf("author cannot be empty")
+	}
+	if tags, err = normalizeTags(nq.Tags); err != nil {
+		return "", "", nil, err
 	}
-	affected, err := res.RowsAffected()
+	return text, author, tags, nil
+}
+
+// nextSeqQuery bumps a channel's quote counter and returns the new number.
+const nextSeqQuery = `INSERT INTO channel_counters(channel, last_seq) VALUES(?, 1)
+                ON CONFLICT(channel) DO UPDATE SET last_seq = last_seq + 1
+                RETURNING last_seq`
+
+// nextChannelSeq allocates the next quote number for channel. Numbers come from a per-channel
+// counter rather than MAX(channel_seq) so a purged quote's number is never handed out again.
+func nextChannelSeq(ctx context.Context, tx *sql.Tx, channel string) (int, error) {
+	var seq int
+	if err := tx.QueryRowContext(ctx, nextSeqQuery, channel).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("allocating quote number: %w", err)
+	}
+	return seq, nil
+}
+
+// withTx runs fn inside a transaction, committing when fn succeeds and rolling back otherwise. A
+// transaction that fails because another process holds the database lock is retried from the
+// start (see retryBusy), so fn must only have effects through tx.
+func (s *QuoteStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	err := s.retryBusy(ctx, func() error {
+		return s.runTx(ctx, fn)
+	})
+	if err == nil {
+		s.notifyChanged()
+	}
+	return err
+}
+
+// runTx runs fn inside a single transaction attempt.
+func (s *QuoteStore) runTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("fetching affected rows: %w", err)
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
 	}
-	if affected == 0 {
-		return fmt.Errorf("no quote with id %d found", id)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
 	}
 	return nil
 }
 
-// UpdateText replaces the text of a quote while leaving the author unchanged.
-func (s *QuoteStore) UpdateText(ctx context.Context, id int, newText string) error {
-	newText = strings.TrimSpace(newText)
-	if newText == "" {
-		return fmt.Errorf("quote text cannot be empty")
-	}
-	res, err := s.db.ExecContext(ctx, "UPDATE quotes SET text = ? WHERE id = ?", newText, id)
+// liveQuoteQuery loads a live quote by channel and number.
+const liveQuoteQuery = "SELECT " + quoteColumns + " FROM quotes WHERE " + liveInChannel + " AND quotes.channel_seq = ?"
+
+// liveQuoteTx loads a quote that has not been deleted, for use as the "before" state of a mutation.
+func liveQuoteTx(ctx context.Context, tx *sql.Tx, channel string, id int) (Quote, error) {
+	q, err := scanQuote(tx.QueryRowContext(ctx, liveQuoteQuery, normalizeChannel(channel), id))
 	if err != nil {
-		return fmt.Errorf("updating quote text: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Quote{}, fmt.Errorf("no quote with id %d found", id)
+		}
+		return Quote{}, fmt.Errorf("fetching quote: %w", err)
 	}
-	affected, err := res.RowsAffected()
+	return q, nil
+}
+
+// List retrieves all live quotes in channel (ordered by ID).
+func (s *QuoteStore) List(ctx context.Context, channel string) ([]Quote, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" ORDER BY quotes.channel_seq", normalizeChannel(channel))
 	if err != nil {
-		return fmt.Errorf("fetching affected rows: %w", err)
+		return nil, fmt.Errorf("listing quotes: %w", err)
 	}
-	if affected == 0 {
-		return fmt.Errorf("no quote with id %d found", id)
+	return scanQuotes(rows)
+}
+
+// Delete moves the quote with the given ID to the trash, recording who deleted it and when.
+// Trashed quotes are hidden from every other query until restored or purged.
+func (s *QuoteStore) Delete(ctx context.Context, channel string, id int, actor string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		old, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET deleted_at = ?, deleted_by = ? WHERE id = ?", formatSQLiteTime(time.Now()), actor, old.RowID); err != nil {
+			return fmt.Errorf("deleting quote: %w", err)
+		}
+		return recordRevision(ctx, tx, snapshotRevision(old, actor, RevisionDelete))
+	})
+}
+
+// UpdateText replaces the text of a quote while leaving the author unchanged.
+func (s *QuoteStore) UpdateText(ctx context.Context, channel string, id int, newText, actor string) error {
+	newText = strings.TrimSpace(newText)
+	if newText == "" {
+		return fmt.Errorf("quote text cannot be empty")
 	}
-	return nil
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		old, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET text = ?, fingerprint = ? WHERE id = ?", newText, quoteFingerprint(newText), old.RowID); err != nil {
+			return fmt.Errorf("updating quote text: %w", err)
+		}
+		rev := snapshotRevision(old, actor, RevisionEdit)
+		rev.NewText = newText
+		return recordRevision(ctx, tx, rev)
+	})
 }
 
 // UpdateAuthor replaces the author of a quote while leaving the text unchanged.
-func (s *QuoteStore) UpdateAuthor(ctx context.Context, id int, newAuthor string) error {
+func (s *QuoteStore) UpdateAuthor(ctx context.Context, channel string, id int, newAuthor, actor string) error {
 	newAuthor = strings.TrimSpace(newAuthor)
 	if newAuthor == "" {
 		return fmt.Errorf("author cannot be empty")
 	}
-	res, err := s.db.ExecContext(ctx, "UPDATE quotes SET author = ? WHERE id = ?", newAuthor, id)
-	if err != nil {
-		return fmt.Errorf("updating quote author: %w", err)
-	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("fetching affected rows: %w", err)
-	}
-	if affected == 0 {
-		return fmt.Errorf("no quote with id %d found", id)
-	}
-	return nil
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		old, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET author = ? WHERE id = ?", newAuthor, old.RowID); err != nil {
+			return fmt.Errorf("updating quote author: %w", err)
+		}
+		rev := snapshotRevision(old, actor, RevisionAuthor)
+		rev.NewAuthor = newAuthor
+		return recordRevision(ctx, tx, rev)
+	})
 }
 
-// GetByID retrieves a quote using its ID.
-func (s *QuoteStore) GetByID(ctx context.Context, id int) (*Quote, error) {
-	row := s.db.QueryRowContext(ctx, "SELECT id, text, author, created_at FROM quotes WHERE id = ?", id)
+// GetByID retrieves a quote in channel using its ID.
+func (s *QuoteStore) GetByID(ctx context.Context, channel string, id int) (*Quote, error) {
+	row := s.read.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" AND quotes.channel_seq = ?", normalizeChannel(channel), id)
 	q, err := scanQuote(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -283,9 +463,9 @@ func (s *QuoteStore) GetByID(ctx context.Context, id int) (*Quote, error) {
 	return &q, nil
 }
 
-// Latest returns the most recently added quote.
-func (s *QuoteStore) Latest(ctx context.Context) (*Quote, error) {
-	row := s.db.QueryRowContext(ctx, "SELECT id, text, author, created_at FROM quotes ORDER BY id DESC LIMIT 1")
+// Latest returns the most recently added quote in channel.
+func (s *QuoteStore) Latest(ctx context.Context, channel string) (*Quote, error) {
+	row := s.read.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE "+liveInChannel+" ORDER BY quotes.channel_seq DESC LIMIT 1", normalizeChannel(channel))
 	q, err := scanQuote(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -296,12 +476,68 @@ func (s *QuoteStore) Latest(ctx context.Context) (*Quote, error) {
 	return &q, nil
 }
 
-// Count returns the total number of quotes stored.
-func (s *QuoteStore) Count(ctx context.Context) (int, error) {
-	row := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM quotes")
+// Count returns the total number of live quotes stored in channel.
+func (s *QuoteStore) Count(ctx context.Context, channel string) (int, error) {
+	row := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM quotes WHERE "+liveInChannel, normalizeChannel(channel))
 	var total int
 	if err := row.Scan(&total); err != nil {
 		return 0, fmt.Errorf("counting quotes: %w", err)
 	}
 	return total, nil
 }
+
+// flagUnscopedAdopted is the store flag set once AdoptUnscopedQuotes has run.
+const flagUnscopedAdopted = "unscoped_quotes_adopted"
+
+// AdoptUnscopedQuotes moves quotes (and their history) that predate channel scoping into channel
+// and returns how many were moved. If channel already has quotes of its own, the adopted quotes are
+// renumbered to follow them so no two quotes in a channel share a number. It runs once per
+// database: later calls, whatever channel they name, move nothing.
+func (s *QuoteStore) AdoptUnscopedQuotes(ctx context.Context, channel string) (int64, error) {
+	channel = normalizeChannel(channel)
+	if channel == "" {
+		return 0, nil
+	}
+
+	var adopted int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		adopted = 0
+		res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO store_flags(name) VALUES (?)", flagUnscopedAdopted)
+		if err != nil {
+			return fmt.Errorf("recording adoption: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("fetching affected rows: %w", err)
+		} else if n == 0 {
+			return nil
+		}
+		var offset int
+		if err := tx.QueryRowContext(ctx, "SELECT COALESCE((SELECT last_seq FROM channel_counters WHERE channel = ?), 0)", channel).Scan(&offset); err != nil {
+			return fmt.Errorf("reading quote counter: %w", err)
+		}
+		res, err = tx.ExecContext(ctx, "UPDATE quotes SET channel = ?, channel_seq = channel_seq + ? WHERE channel = ''", channel, offset)
+		if err != nil {
+			return fmt.Errorf("adopting quotes: %w", err)
+		}
+		if adopted, err = res.RowsAffected(); err != nil {
+			return fmt.Errorf("fetching affected rows: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quote_revisions SET channel = ?, channel_seq = channel_seq + ? WHERE channel = ''", channel, offset); err != nil {
+			return fmt.Errorf("adopting history: %w", err)
+		}
+		const mergeCounters = `INSERT INTO channel_counters(channel, last_seq)
+                SELECT ?, last_seq + ? FROM channel_counters WHERE channel = ''
+                ON CONFLICT(channel) DO UPDATE SET last_seq = excluded.last_seq`
+		if _, err := tx.ExecContext(ctx, mergeCounters, channel, offset); err != nil {
+			return fmt.Errorf("merging quote counters: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM channel_counters WHERE channel = ''"); err != nil {
+			return fmt.Errorf("merging quote counters: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return adopted, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamInfo describes what a channel is streaming when a quote is captured.
+type StreamInfo struct {
+	Game  string
+	Title string
+}
+
+// StreamInfoProvider looks up the current category and title of a channel.
+type StreamInfoProvider interface {
+	StreamInfo(ctx context.Context, channel string) (StreamInfo, error)
+}
+
+// StaticStreamInfo is a StreamInfoProvider backed by a fixed map from channel to info. It is meant
+// for tests and offline use; unknown channels report empty info.
+type StaticStreamInfo map[string]StreamInfo
+
+// StreamInfo implements StreamInfoProvider.
+func (s StaticStreamInfo) StreamInfo(_ context.Context, channel string) (StreamInfo, error) {
+	return s[normalizeChannel(channel)], nil
+}
+
+// streamInfoOverride wraps a provider with per-channel manual game overrides set from chat.
+// The base provider may be nil, in which case only overrides are reported.
+type streamInfoOverride struct {
+	base StreamInfoProvider
+
+	mu    sync.Mutex
+	games map[string]string
+}
+
+func newStreamInfoOverride(base StreamInfoProvider) *streamInfoOverride {
+	return &streamInfoOverride{base: base, games: make(map[string]string)}
+}
+
+// SetGame overrides the game reported for channel; an empty game removes the override.
+func (o *streamInfoOverride) SetGame(channel, game string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	channel = normalizeChannel(channel)
+	if game == "" {
+		delete(o.games, channel)
+		return
+	}
+	o.games[channel] = game
+}
+
+// Override returns the manual game for channel, if one is set.
+func (o *streamInfoOverride) Override(channel string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	game, ok := o.games[normalizeChannel(channel)]
+	return game, ok
+}
+
+// StreamInfo implements StreamInfoProvider, preferring a manual game over the base provider's.
+func (o *streamInfoOverride) StreamInfo(ctx context.Context, channel string) (StreamInfo, error) {
+	var info StreamInfo
+	var err error
+	if o.base != nil {
+		info, err = o.base.StreamInfo(ctx, channel)
+	}
+	if game, ok := o.Override(channel); ok {
+		info.Game = game
+		err = nil
+	}
+	return info, err
+}
+
+// streamInfoTimeout bounds a stream info lookup, so a slow Twitch API leaves the rest of a chat
+// command's deadline for saving the quote.
+const streamInfoTimeout = 2 * time.Second
+
+// helixStreamInfoCacheTTL is how long a looked-up category and title are reused before asking Twitch again.
+const helixStreamInfoCacheTTL = time.Minute
+
+// helixFollowCacheTTL is how long a looked-up follow is reused before asking Twitch again.
+const helixFollowCacheTTL = 10 * time.Minute
+
+// helixStreamInfo fetches the channel category and title, and who follows the channel, from the
+// Twitch Helix API.
+type helixStreamInfo struct {
+	clientID string
+	token    string
+	baseURL  string
+	client   *http.Client
+
+	mu           sync.Mutex
+	broadcasters map[string]string
+	cache        map[string]cachedStreamInfo
+	follows      map[string]cachedFollow
+}
+
+type cachedStreamInfo struct {
+	info    StreamInfo
+	fetched time.Time
+}
+
+type cachedFollow struct {
+	followedAt time.Time
+	following  bool
+	fetched    time.Time
+}
+
+// newHelixStreamInfo returns a Helix-backed provider, or nil when no client ID is configured.
+// The OAuth token may be given with or without its "oauth:" prefix.
+func newHelixStreamInfo(clientID, oauth string) StreamInfoProvider {
+	clientID = strings.TrimSpace(clientID)
+	if clientID == "" {
+		return nil
+	}
+	return &helixStreamInfo{
+		clientID:     clientID,
+		token:        strings.TrimPrefix(strings.TrimSpace(oauth), "oauth:"),
+		baseURL:      "https://api.twitch.tv/helix",
+		client:       &http.Client{Timeout: streamInfoTimeout},
+		broadcasters: make(map[string]string),
+		cache:        make(map[string]cachedStreamInfo),
+		follows:      make(map[string]cachedFollow),
+	}
+}
+
+// StreamInfo implements StreamInfoProvider using the Get Channel Information endpoint, which
+// reports the last category and title even while the channel is offline.
+func (h *helixStreamInfo) StreamInfo(ctx context.Context, channel string) (StreamInfo, error) {
+	channel = normalizeChannel(channel)
+	h.mu.Lock()
+	cached, ok := h.cache[channel]
+	h.mu.Unlock()
+	if ok && time.Since(cached.fetched) < helixStreamInfoCacheTTL {
+		return cached.info, nil
+	}
+
+	broadcasterID, err := h.broadcasterID(ctx, channel)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+
+	var resp struct {
+		Data []struct {
+			GameName string `json:"game_name"`
+			Title    string `json:"title"`
+		} `json:"data"`
+	}
+	if err := h.get(ctx, "/channels", url.Values{"broadcaster_id": {broadcasterID}}, &resp); err != nil {
+		return StreamInfo{}, fmt.Errorf("fetching channel information: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return StreamInfo{}, fmt.Errorf("no channel information for %s", channel)
+	}
+	info := StreamInfo{Game: resp.Data[0].GameName, Title: resp.Data[0].Title}
+
+	h.mu.Lock()
+	h.cache[channel] = cachedStreamInfo{info: info, fetched: time.Now()}
+	h.mu.Unlock()
+	return info, nil
+}
+
+// FollowedAt implements FollowerLookup using the Get Channel Followers endpoint, which needs a
+// token with the moderator:read:followers scope from a moderator of the channel.
+func (h *helixStreamInfo) FollowedAt(ctx context.Context, channel, userID string) (time.Time, bool, error) {
+	channel = normalizeChannel(channel)
+	key := channel + "/" + userID
+	h.mu.Lock()
+	cached, ok := h.follows[key]
+	h.mu.Unlock()
+	if ok && time.Since(cached.fetched) < helixFollowCacheTTL {
+		return cached.followedAt, cached.following, nil
+	}
+
+	broadcasterID, err := h.broadcasterID(ctx, channel)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	var resp struct {
+		Data []struct {
+			FollowedAt time.Time `json:"followed_at"`
+		} `json:"data"`
+	}
+	query := url.Values{"broadcaster_id": {broadcasterID}, "user_id": {userID}}
+	if err := h.get(ctx, "/channels/followers", query, &resp); err != nil {
+		return time.Time{}, false, fmt.Errorf("fetching channel followers: %w", err)
+	}
+	follow := cachedFollow{following: len(resp.Data) > 0, fetched: time.Now()}
+	if follow.following {
+		follow.followedAt = resp.Data[0].FollowedAt
+	}
+
+	h.mu.Lock()
+	h.follows[key] = follow
+	h.mu.Unlock()
+	return follow.followedAt, follow.following, nil
+}
+
+func (h *helixStreamInfo) broadcasterID(ctx context.Context, login string) (string, error) {
+	h.mu.Lock()
+	id, ok := h.broadcasters[login]
+	h.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := h.get(ctx, "/users", url.Values{"login": {login}}, &resp); err != nil {
+		return "", fmt.Errorf("looking up broadcaster: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("no Twitch user named %s", login)
+	}
+
+	h.mu.Lock()
+	h.broadcasters[login] = resp.Data[0].ID
+	h.mu.Unlock()
+	return resp.Data[0].ID, nil
+}
+
+func (h *helixStreamInfo) get(ctx context.Context, path string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Client-Id", h.clientID)
+	req.Header.Set("Authorization", "Bearer "+h.token)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("helix %s returned %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding helix response: %w", err)
+	}
+	return nil
+}
+
+// normalizeChannel lower-cases a channel name and strips a leading '#'.
+func normalizeChannel(channel string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(channel), "#"))
+}
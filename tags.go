@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// RevisionTag and RevisionUntag record tag changes in a quote's history; the note lists the tags.
+const (
+	RevisionTag   = "tag"
+	RevisionUntag = "untag"
+)
+
+// maxTagLength bounds tag names so tag listings stay readable in chat.
+const maxTagLength = 32
+
+// TagCount is a tag together with the number of live quotes carrying it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// normalizeTag lower-cases a tag and strips a leading '#'. It reports false when the result is
+// empty, too long, or contains characters other than letters, digits, '_' and '-'.
+func normalizeTag(raw string) (string, bool) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(raw), "#"))
+	if tag == "" || len([]rune(tag)) > maxTagLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+// normalizeTags normalizes and de-duplicates tags, returning an error naming the first invalid one.
+func normalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, r := range raw {
+		tag, ok := normalizeTag(r)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q (letters, digits, _ and - only, up to %d characters)", r, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// splitHashtags removes the run of #hashtags at the end of a quote and returns the remaining text
+// and the tags. Hashtags in the middle of a quote are treated as part of what was said.
+func splitHashtags(text string) (string, []string) {
+	words := strings.Fields(text)
+	end := len(words)
+	for end > 0 {
+		word := words[end-1]
+		if !strings.HasPrefix(word, "#") {
+			break
+		}
+		if _, ok := normalizeTag(word); !ok {
+			break
+		}
+		end--
+	}
+	if end == len(words) || end == 0 {
+		return strings.TrimSpace(text), nil
+	}
+	tags := make([]string, 0, len(words)-end)
+	for _, word := range words[end:] {
+		tag, _ := normalizeTag(word)
+		tags = append(tags, tag)
+	}
+	return strings.Join(words[:end], " "), tags
+}
+
+// formatTags renders tags as space-separated hashtags.
+func formatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "#" + tag
+	}
+	return strings.Join(parts, " ")
+}
+
+// AddTags attaches tags to a live quote in channel and returns the tags that were newly added.
+func (s *QuoteStore) AddTags(ctx context.Context, channel string, id int, tags []string, actor string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags given")
+	}
+
+	var added []string
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		q, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		if added, err = addTagsTx(ctx, tx, q.RowID, tags); err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		rev := snapshotRevision(q, actor, RevisionTag)
+		rev.Note = formatTags(added)
+		return recordRevision(ctx, tx, rev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// addTagsTx links already-normalized tags to the quote with the given row ID, creating tags as
+// needed, and returns the tags that were not already present.
+func addTagsTx(ctx context.Context, tx *sql.Tx, rowID int64, tags []string) ([]string, error) {
+	var added []string
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO tags(name) VALUES(?)", tag); err != nil {
+			return nil, fmt.Errorf("creating tag: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO quote_tags(quote_id, tag_id) SELECT ?, id FROM tags WHERE name = ?", rowID, tag)
+		if err != nil {
+			return nil, fmt.Errorf("tagging quote: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("fetching affected rows: %w", err)
+		}
+		if affected > 0 {
+			added = append(added, tag)
+		}
+	}
+	return added, nil
+}
+
+// RemoveTags detaches tags from a live quote in channel and returns the tags that were actually removed.
+func (s *QuoteStore) RemoveTags(ctx context.Context, channel string, id int, tags []string, actor string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags given")
+	}
+
+	var removed []string
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		q, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			res, err := tx.ExecContext(ctx, "DELETE FROM quote_tags WHERE quote_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)", q.RowID, tag)
+			if err != nil {
+				return fmt.Errorf("untagging quote: %w", err)
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("fetching affected rows: %w", err)
+			}
+			if affected > 0 {
+				removed = append(removed, tag)
+			}
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		rev := snapshotRevision(q, actor, RevisionUntag)
+		rev.Note = formatTags(removed)
+		return recordRevision(ctx, tx, rev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// TagsFor returns the tags attached to a quote in channel in alphabetical order.
+func (s *QuoteStore) TagsFor(ctx context.Context, channel string, id int) ([]string, error) {
+	const query = `SELECT tags.name FROM quote_tags
+                JOIN tags ON tags.id = quote_tags.tag_id
+                JOIN quotes ON quotes.id = quote_tags.quote_id
+                WHERE quotes.channel = ? AND quotes.channel_seq = ? ORDER BY tags.name`
+	rows, err := s.read.QueryContext(ctx, query, normalizeChannel(channel), id)
+	if err != nil {
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tags: %w", err)
+	}
+	return tags, nil
+}
+
+// TopTags returns up to limit tags ordered by how many live quotes in channel use them.
+func (s *QuoteStore) TopTags(ctx context.Context, channel string, limit int) ([]TagCount, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	const query = `SELECT tags.name, COUNT(*) AS uses FROM quote_tags
+                JOIN tags ON tags.id = quote_tags.tag_id
+                JOIN quotes ON quotes.id = quote_tags.quote_id
+                WHERE ` + liveInChannel + `
+                GROUP BY tags.id ORDER BY uses DESC, tags.name LIMIT ?`
+	rows, err := s.read.QueryContext(ctx, query, normalizeChannel(channel), limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []TagCount
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Name, &tc.Count); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		counts = append(counts, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tags: %w", err)
+	}
+	if len(counts) == 0 {
+		return nil, ErrNoQuotes
+	}
+	return counts, nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// replyData holds the fields a reply template is executed with, by name.
+type replyData map[string]any
+
+// responseSpec describes a chat reply: its default template and the fields it is given besides
+// prefix, which every reply has.
+type responseSpec struct {
+	text   string
+	fields []string
+}
+
+// quoteFields are the fields of every reply about a quote. All but the quote format itself also
+// get quote, the quote rendered with the channel's quote format.
+var quoteFields = []string{"id", "text", "author", "game", "title", "submitter", "platform", "score", "views", "date", "ago"}
+
+// aboutQuote returns the fields of a reply about a quote, plus fields.
+func aboutQuote(fields ...string) []string {
+	return append(append([]string{"quote"}, quoteFields...), fields...)
+}
+
+// responses are the chat replies response_templates can change, by name. The defaults are Go
+// text/template templates executed with the fields listed for each.
+var responses = map[string]responseSpec{
+	// Quote formats.
+	"quote":         {`#{{.id}}: "{{.text}}" - {{.author}}`, quoteFields},
+	"quote_trashed": {`{{.quote}} (deleted by {{.deleted_by}})`, aboutQuote("deleted_by")},
+	"quote_pending": {`{{.quote}} (from {{.submitter}})`, aboutQuote()},
+	"quote_scored":  {`{{.quote}} ({{.score}})`, aboutQuote()},
+
+	// Shared replies.
+	"usage":              {`Usage: {{.usage}}`, []string{"usage", "command"}},
+	"invalid_id":         {`Invalid quote ID.`, []string{"command"}},
+	"invalid_pending_id": {`Invalid pending ID.`, []string{"command"}},
+	"denied":             {`Only {{.role}} can use {{.prefix}} {{.command}}.`, []string{"role", "command", "user"}},
+	"cooldown":           {`@{{.user}} please wait {{.seconds}}s before using {{.prefix}} {{.command}} again.`, []string{"seconds", "command", "user"}},
+	"help":               {`Commands: {{.commands}}. Try {{.prefix}} help <command> for details.`, []string{"commands"}},
+	"help_unknown":       {`Unknown command {{printf "%q" .topic}}. Use {{.prefix}} help to list them.`, []string{"topic"}},
+	"error":              {`Error {{.action}}: {{.error}}`, []string{"action", "error"}},
+	"empty":              {`No quotes have been added yet.`, nil},
+
+	// Showing quotes.
+	"random":          {`{{.quote}}`, aboutQuote()},
+	"random_empty":    {`No quotes have been added yet. Try {{.prefix}} add to add one!`, nil},
+	"random_no_match": {`No quotes match that filter.`, []string{"tag", "game"}},
+	"by_none":         {`No quotes by {{.author}} found.`, []string{"author"}},
+	"submitted_none":  {`{{.submitter}} has not submitted any quotes.`, []string{"submitter"}},
+	"get":             {`{{.quote}}{{with .context}} {{.}}{{end}}{{with .submitted}} ({{.}}){{end}} {{.added}}{{if ne .score "0"}} [score {{.score}}]{{end}}{{with .tags}} {{.}}{{end}}`, aboutQuote("context", "submitted", "added", "tags")},
+	"get_none":        {`No quote with ID #{{.id}} found.`, []string{"id"}},
+	"latest":          {`Latest is {{.quote}} (added {{.ago}})`, aboutQuote()},
+	"search":          {`{{.quote}}{{if gt .total 1}} (best of {{.total}} matches){{end}}`, aboutQuote("total", "query")},
+	"search_none":     {`No matching quotes found.`, []string{"query"}},
+	"search_invalid":  {`Could not understand that search: {{.error}}`, []string{"query", "error"}},
+	"count":           {`There {{if eq .count 1}}is{{else}}are{{end}} {{.count}} {{plural .count "quote" "quotes"}} saved.`, []string{"count"}},
+	"list":            {`{{with .filter}}{{.}}, page{{else}}Page{{end}} {{.page}}/{{.pages}}: {{.quotes}}`, []string{"filter", "page", "pages", "quotes"}},
+	"list_none":       {`No {{with .filter}}{{lower .}}{{else}}quotes{{end}} found.`, []string{"filter"}},
+	"list_beyond":     {`There {{if eq .pages 1}}is{{else}}are{{end}} only {{.pages}} {{plural .pages "page" "pages"}}.`, []string{"pages"}},
+	"top":             {`Top quotes: {{.quotes}}`, []string{"quotes"}},
+	"top_none":        {`No quotes have been voted up yet. Use {{.prefix}} +1 <id> to vote.`, nil},
+	"worst":           {`Lowest rated: {{.quotes}}`, []string{"quotes"}},
+	"worst_none":      {`No quotes have been voted down yet.`, nil},
+	"stats":           {`{{.total}} {{plural .total "quote" "quotes"}} by {{.authors}} {{plural .authors "author" "authors"}}, shown {{.views}} {{plural .views "time" "times"}}{{if .never_shown}} ({{.never_shown}} never shown){{end}}. Top authors: {{.top_authors}}.{{with .most_shown}} Most shown: {{.}}.{{end}}`, []string{"total", "authors", "views", "never_shown", "top_authors", "most_shown"}},
+	"tags":            {`Top tags: {{.tags}}`, []string{"tags"}},
+	"tags_none":       {`No quotes have been tagged yet.`, nil},
+	"quote_tags":      {`Quote #{{.id}}: {{.tags}}`, []string{"id", "tags"}},
+	"quote_tags_none": {`Quote #{{.id}} has no tags.`, []string{"id"}},
+	"history":         {`Quote #{{.id}} history: {{.history}}`, []string{"id", "history"}},
+	"history_none":    {`No history recorded for quote #{{.id}}.`, []string{"id"}},
+	"game":            {`New quotes will be recorded as {{.game}}.`, []string{"game"}},
+	"game_manual":     {`Game is manually set to {{.game}}. Use {{.prefix}} setgame auto to go back to Twitch.`, []string{"game"}},
+	"game_unknown":    {`No game is known for this stream. Mods can set one with {{.prefix}} setgame <name>.`, nil},
+	"game_cleared":    {`Manual game cleared; using Twitch's category again.`, nil},
+	"trash":           {`Recently deleted: {{.quotes}}`, []string{"quotes"}},
+	"trash_empty":     {`The trash is empty.`, nil},
+	"pending":         {`{{.total}} waiting for approval: {{.quotes}}`, []string{"total", "quotes"}},
+	"pending_none":    {`No quotes are waiting for approval.`, nil},
+	"vote":            {`Quote #{{.id}} now has a score of {{.score}}.`, []string{"id", "score", "vote", "user"}},
+	"vote_unchanged":  {`You already voted {{.vote}} on quote #{{.id}} (score {{.score}}).`, []string{"id", "score", "vote", "user"}},
+
+	// Changing quotes.
+	"added":             {`Quote added with ID #{{.id}}{{with .tags}} (tagged {{.}}){{end}}.{{with .similar_id}} Heads up: it looks a lot like #{{.}}.{{end}}`, []string{"id", "tags", "similar_id", "user"}},
+	"submitted":         {`Thanks! Your quote is waiting for moderator approval (pending #{{.pending_id}}).`, []string{"pending_id", "user"}},
+	"duplicate":         {`Already saved as #{{.id}}.`, []string{"id"}},
+	"duplicate_pending": {`Already waiting for approval as pending #{{.id}}.`, []string{"id"}},
+	"deleted":           {`Quote #{{.id}} moved to the trash. Use {{.prefix}} restore {{.id}} to undo.`, []string{"id", "user"}},
+	"restore_duplicate": {`Cannot restore quote #{{.id}}: {{.reason}}`, []string{"id", "reason", "user"}},
+	"restored":          {`Quote #{{.id}} restored.`, []string{"id", "user"}},
+	"edited":            {`Quote #{{.id}} updated.`, []string{"id", "user"}},
+	"author_changed":    {`Quote #{{.id}} author updated to {{.author}}.`, []string{"id", "author", "user"}},
+	"tagged":            {`Quote #{{.id}} tagged {{.tags}}.`, []string{"id", "tags", "user"}},
+	"tagged_none":       {`Quote #{{.id}} already has those tags.`, []string{"id", "user"}},
+	"untagged":          {`Removed {{.tags}} from quote #{{.id}}.`, []string{"id", "tags", "user"}},
+	"untagged_none":     {`Quote #{{.id}} has none of those tags.`, []string{"id", "user"}},
+	"approved":          {`Pending #{{.id}} approved as quote #{{.quote_id}}.{{with .similar_id}} Heads up: it looks a lot like #{{.}}.{{end}}`, []string{"id", "quote_id", "similar_id", "user"}},
+	"approve_duplicate": {`Cannot approve pending #{{.id}}: {{.reason}}`, []string{"id", "reason", "user"}},
+	"rejected":          {`Pending #{{.id}} rejected.`, []string{"id", "user"}},
+}
+
+// templateFuncs are the functions response templates can call besides text/template's built-ins.
+var templateFuncs = template.FuncMap{
+	"plural": func(count int, single, plural string) string { return pluralize(single, plural, count) },
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	// truncate shortens text to limit bytes, e.g. {{.text | truncate 50}}.
+	"truncate": func(limit int, text string) string { return truncate(text, limit) },
+}
+
+// defaultResponses holds the parsed default of every reply in responses.
+var defaultResponses = func() map[string]*template.Template {
+	parsed := make(map[string]*template.Template, len(responses))
+	for name, spec := range responses {
+		tmpl, err := parseResponse(name, spec.text)
+		if err != nil {
+			panic(err)
+		}
+		parsed[name] = tmpl
+	}
+	return parsed
+}()
+
+// ResponseTemplates holds a channel's replacements for the default replies. The zero value uses
+// the defaults.
+type ResponseTemplates struct {
+	texts     map[string]string
+	templates map[string]*template.Template
+}
+
+// parseResponseTemplates validates the configured templates, keyed by reply name, by executing
+// each against sample data with the fields of its reply. Using a field the reply does not have is
+// an error.
+func parseResponseTemplates(texts map[string]string) (ResponseTemplates, error) {
+	t := ResponseTemplates{texts: make(map[string]string), templates: make(map[string]*template.Template)}
+	for name, text := range texts {
+		name = strings.ToLower(strings.TrimSpace(name))
+		tmpl, err := parseResponse(name, text)
+		if err != nil {
+			return ResponseTemplates{}, fmt.Errorf("response_templates: %w", err)
+		}
+		t.texts[name] = text
+		t.templates[name] = tmpl
+	}
+	return t, nil
+}
+
+// parseResponse parses text as the template of the reply called name and checks it against sample
+// data.
+func parseResponse(name, text string) (*template.Template, error) {
+	spec, ok := responses[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown reply %q", name)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, sampleReplyData(spec.fields)); err != nil {
+		return nil, fmt.Errorf("%s: %w (fields: %s)", name, err, strings.Join(spec.fields, ", "))
+	}
+	return tmpl, nil
+}
+
+// Text returns the template the reply called name uses, and whether it replaces the default.
+func (t ResponseTemplates) Text(name string) (string, bool) {
+	if text, ok := t.texts[name]; ok {
+		return text, true
+	}
+	return responses[name].text, false
+}
+
+// render executes the reply called name with data, falling back to the default template if the
+// configured one fails.
+func (t ResponseTemplates) render(name string, data replyData) string {
+	var sb strings.Builder
+	if tmpl, ok := t.templates[name]; ok {
+		err := tmpl.Execute(&sb, data)
+		if err == nil {
+			return sb.String()
+		}
+		log.Printf("Error rendering reply %s: %v", name, err)
+		sb.Reset()
+	}
+	if err := defaultResponses[name].Execute(&sb, data); err != nil {
+		log.Printf("Error rendering default reply %s: %v", name, err)
+	}
+	return sb.String()
+}
+
+// responseNames returns the names of every reply, sorted.
+func responseNames() []string {
+	names := make([]string, 0, len(responses))
+	for name := range responses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sampleQuote is the quote templates are validated and previewed with.
+func sampleQuote() Quote {
+	return Quote{
+		ID:          42,
+		Text:        "I'm not lost, I'm exploring.",
+		Author:      "Streamer",
+		Game:        "Elden Ring",
+		StreamTitle: "Blind run, no spoilers!",
+		SubmittedBy: "Viewer",
+		Platform:    PlatformTwitch,
+		Score:       3,
+		Views:       7,
+		CreatedAt:   time.Now().Add(-72 * time.Hour),
+	}
+}
+
+// sampleValues are the values of fields that are not a quote's, for validation and previews.
+var sampleValues = replyData{
+	"quote":       `#42: "I'm not lost, I'm exploring." - Streamer`,
+	"usage":       commandPrefix + " get <id>",
+	"command":     "get",
+	"role":        RoleModerator.plural(),
+	"user":        "Viewer",
+	"seconds":     12,
+	"topic":       "frobnicate",
+	"commands":    "add, get, search, list, help",
+	"action":      "fetching quote #42",
+	"error":       "database is locked",
+	"tag":         "#rage",
+	"context":     `(playing Elden Ring, "Blind run, no spoilers!")`,
+	"submitted":   "submitted by Viewer via twitch",
+	"added":       "added 3 days ago",
+	"tags":        "#rage #speedrun",
+	"total":       3,
+	"query":       "lost",
+	"count":       3,
+	"filter":      "Quotes by Streamer",
+	"page":        1,
+	"pages":       2,
+	"quotes":      `#42: "I'm not lost, I'm exploring." - Streamer | #43: "Again?" - Streamer`,
+	"authors":     2,
+	"never_shown": 1,
+	"top_authors": "Streamer (2), Viewer (1)",
+	"most_shown":  "#42 (7 views)",
+	"history":     "r7 added by Viewer 3 days ago",
+	"vote":        "+1",
+	"deleted_by":  "Moderator",
+	"similar_id":  17,
+	"pending_id":  5,
+	"quote_id":    43,
+	"reason":      "Already saved as #42.",
+}
+
+// sampleReplyData returns sample data with the given fields and the prefix.
+func sampleReplyData(fields []string) replyData {
+	all := ChannelSettings{}.quoteData(sampleQuote())
+	for name, value := range sampleValues {
+		all[name] = value
+	}
+	data := replyData{"prefix": commandPrefix}
+	for _, field := range fields {
+		value, ok := all[field]
+		if !ok {
+			panic(fmt.Sprintf("no sample value for reply field %q", field))
+		}
+		data[field] = value
+	}
+	return data
+}
+
+// quoteData returns the fields describing q in the channel.
+func (s ChannelSettings) quoteData(q Quote) replyData {
+	return replyData{
+		"id":        q.ID,
+		"text":      q.Text,
+		"author":    q.Author,
+		"game":      q.Game,
+		"title":     q.StreamTitle,
+		"submitter": q.SubmittedBy,
+		"platform":  q.Platform,
+		"score":     formatScore(q.Score),
+		"views":     q.Views,
+		"date":      s.Display.Format(q.CreatedAt),
+		"ago":       relativeTime(q.CreatedAt, time.Now()),
+	}
+}
+
+// FormatQuote renders q with the channel's quote format.
+func (s ChannelSettings) FormatQuote(q Quote) string {
+	return s.reply("quote", s.quoteData(q))
+}
+
+// reply renders the reply called name with data and the channel's prefix.
+func (s ChannelSettings) reply(name string, data replyData) string {
+	if data == nil {
+		data = replyData{}
+	}
+	data["prefix"] = s.Triggers.prefix()
+	return s.Templates.render(name, data)
+}
+
+// replyQuote renders the reply called name about q: data plus q's fields and q in the channel's
+// quote format.
+func (s ChannelSettings) replyQuote(name string, q Quote, data replyData) string {
+	fields := s.quoteData(q)
+	fields["quote"] = s.FormatQuote(q)
+	for key, value := range data {
+		fields[key] = value
+	}
+	return s.reply(name, fields)
+}
+
+// PreviewResponse renders the reply called name against sample data, using text instead of the
+// channel's template when it is not empty.
+func (s ChannelSettings) PreviewResponse(name, text string) (string, error) {
+	spec, ok := responses[name]
+	if !ok {
+		return "", fmt.Errorf("unknown reply %q", name)
+	}
+	templates := s.Templates
+	if text != "" {
+		tmpl, err := parseResponse(name, text)
+		if err != nil {
+			return "", err
+		}
+		templates = ResponseTemplates{templates: map[string]*template.Template{name: tmpl}}
+		for key, value := range s.Templates.templates {
+			if key != name {
+				templates.templates[key] = value
+			}
+		}
+	}
+	preview := s
+	preview.Templates = templates
+	data := sampleReplyData(spec.fields)
+	if _, ok := data["quote"]; ok {
+		data["quote"] = preview.FormatQuote(sampleQuote())
+	}
+	return preview.reply(name, data), nil
+}
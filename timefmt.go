@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Embed the time zone database so channel time zones resolve on systems without one (Windows).
+	_ "time/tzdata"
+)
+
+// defaultDateFormat is the layout used when neither the channel nor the config sets date_format.
+const defaultDateFormat = "2006-01-02 15:04 MST"
+
+// dateFormatPresets are the named formats date_format accepts in addition to a Go reference layout.
+var dateFormatPresets = map[string]string{
+	"iso":  defaultDateFormat,
+	"us":   "Jan 2, 2006 3:04 PM MST",
+	"eu":   "2 Jan 2006 15:04 MST",
+	"date": "2006-01-02",
+}
+
+// TimeDisplay is how timestamps are shown to one channel: converted to Location and formatted
+// with Layout.
+type TimeDisplay struct {
+	Location *time.Location
+	Layout   string
+}
+
+// Format renders t in the display's time zone and layout. The zero TimeDisplay uses the local
+// time zone and defaultDateFormat.
+func (d TimeDisplay) Format(t time.Time) string {
+	loc := d.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	layout := d.Layout
+	if layout == "" {
+		layout = defaultDateFormat
+	}
+	return t.In(loc).Format(layout)
+}
+
+// Added describes when a quote was added, e.g. "added 2026-10-13 20:01 CEST (3 days ago)".
+func (d TimeDisplay) Added(t, now time.Time) string {
+	return fmt.Sprintf("added %s (%s)", d.Format(t), relativeTime(t, now))
+}
+
+// parseTimeDisplay resolves a time zone name ("" for the local zone, "UTC" or an IANA name such as
+// "Europe/Berlin") and a date format (a preset name, a Go reference layout, or "" for the default).
+func parseTimeDisplay(timezone, format string) (TimeDisplay, error) {
+	display := TimeDisplay{Location: time.Local, Layout: defaultDateFormat}
+	if name := strings.TrimSpace(timezone); name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return TimeDisplay{}, fmt.Errorf("unknown timezone %q", name)
+		}
+		display.Location = loc
+	}
+	if format = strings.TrimSpace(format); format != "" {
+		if preset, ok := dateFormatPresets[strings.ToLower(format)]; ok {
+			format = preset
+		}
+		// A layout without any reference-time element prints itself unchanged for any other date.
+		sample := time.Date(2001, time.November, 12, 13, 14, 15, 0, time.UTC)
+		if sample.Format(format) == format {
+			return TimeDisplay{}, fmt.Errorf("date format %q has no date or time elements (use iso, us, eu, date or a Go layout such as 2006-01-02)", format)
+		}
+		display.Layout = format
+	}
+	return display, nil
+}
+
+// DisplaySettings holds the time display of every configured channel.
+type DisplaySettings struct {
+	Default  TimeDisplay
+	Channels map[string]TimeDisplay
+}
+
+// For returns the time display for channel, falling back to the default.
+func (s DisplaySettings) For(channel string) TimeDisplay {
+	if d, ok := s.Channels[normalizeChannel(channel)]; ok {
+		return d
+	}
+	return s.Default
+}
+
+// relativeTime describes how long before now t was, e.g. "just now", "5 minutes ago" or
+// "3 days ago". Months count as 30 days and years as 365. Times in the future (clock skew) read as
+// "just now".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	const day = 24 * time.Hour
+	ago := func(n int, unit string) string {
+		return fmt.Sprintf("%d %s%s ago", n, unit, pluralSuffix(n))
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return ago(int(d/time.Minute), "minute")
+	case d < day:
+		return ago(int(d/time.Hour), "hour")
+	case d < 2*day:
+		return "yesterday"
+	case d < 30*day:
+		return ago(int(d/day), "day")
+	case d < 365*day:
+		return ago(int(d/(30*day)), "month")
+	default:
+		return ago(int(d/(365*day)), "year")
+	}
+}
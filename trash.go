@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultTrashRetentionDays is how long deleted quotes stay restorable when the config does not say otherwise.
+const defaultTrashRetentionDays = 30
+
+// trashPurgeInterval is how often a long-running process re-applies the purge policy.
+const trashPurgeInterval = 24 * time.Hour
+
+// Restore moves a trashed quote in channel back into circulation. Like Add, it returns a
+// *DuplicateQuoteError when a live quote in the channel has the same fingerprint.
+func (s *QuoteStore) Restore(ctx context.Context, channel string, id int, actor string) error {
+	channel = normalizeChannel(channel)
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var rowID int64
+		var fingerprint string
+		err := tx.QueryRowContext(ctx, "SELECT id, fingerprint FROM quotes WHERE channel = ? AND channel_seq = ? AND deleted_at IS NOT NULL", channel, id).Scan(&rowID, &fingerprint)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no deleted quote with id %d found", id)
+		}
+		if err != nil {
+			return fmt.Errorf("fetching deleted quote: %w", err)
+		}
+		if err := duplicateTx(ctx, tx, channel, fingerprint); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET deleted_at = NULL, deleted_by = NULL WHERE id = ?", rowID); err != nil {
+			return fmt.Errorf("restoring quote: %w", err)
+		}
+		restored, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, snapshotRevision(restored, actor, RevisionRestore))
+	})
+}
+
+// Trash returns up to limit deleted quotes in channel, most recently deleted first.
+func (s *QuoteStore) Trash(ctx context.Context, channel string, limit int) ([]Quote, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.read.QueryContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE channel = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT ?", normalizeChannel(channel), limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing trash: %w", err)
+	}
+	return scanQuotes(rows)
+}
+
+// GetDeleted retrieves a trashed quote in channel by ID.
+func (s *QuoteStore) GetDeleted(ctx context.Context, channel string, id int) (*Quote, error) {
+	row := s.read.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE channel = ? AND channel_seq = ? AND deleted_at IS NOT NULL", normalizeChannel(channel), id)
+	q, err := scanQuote(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoQuotes
+		}
+		return nil, fmt.Errorf("fetching deleted quote: %w", err)
+	}
+	return &q, nil
+}
+
+// PurgeTrash permanently removes quotes in every channel that were deleted before cutoff and returns
+// how many were removed. Each purge is recorded in the quote's history, which outlives the quote itself.
+func (s *QuoteStore) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		const record = `INSERT INTO quote_revisions(quote_id, channel, channel_seq, actor, action, old_text, old_author, new_text, new_author, created_at)
+                SELECT id, channel, channel_seq, 'system', ?, text, author, text, author, ? FROM quotes WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+		if _, err := tx.ExecContext(ctx, record, RevisionPurge, formatSQLiteTime(time.Now()), formatSQLiteTime(cutoff)); err != nil {
+			return fmt.Errorf("recording purge: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM quote_tags WHERE quote_id IN (SELECT id FROM quotes WHERE deleted_at IS NOT NULL AND deleted_at < ?)", formatSQLiteTime(cutoff)); err != nil {
+			return fmt.Errorf("purging tags: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM shuffle_drawn WHERE quote_id IN (SELECT id FROM quotes WHERE deleted_at IS NOT NULL AND deleted_at < ?)", formatSQLiteTime(cutoff)); err != nil {
+			return fmt.Errorf("purging shuffle bag: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM quote_votes WHERE quote_id IN (SELECT id FROM quotes WHERE deleted_at IS NOT NULL AND deleted_at < ?)", formatSQLiteTime(cutoff)); err != nil {
+			return fmt.Errorf("purging votes: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM quotes WHERE deleted_at IS NOT NULL AND deleted_at < ?", formatSQLiteTime(cutoff))
+		if err != nil {
+			return fmt.Errorf("purging trash: %w", err)
+		}
+		purged, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("fetching affected rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// trashRetention converts the configured retention in days into a duration.
+// A negative value disables purging and reports false.
+func trashRetention(days int) (time.Duration, bool) {
+	if days < 0 {
+		return 0, false
+	}
+	if days == 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour, true
+}
+
+// runTrashPurger applies the purge policy immediately and then once per trashPurgeInterval
+// until ctx is canceled. It is a no-op when purging is disabled.
+func runTrashPurger(ctx context.Context, store QuoteRepository, retentionDays int) {
+	retention, ok := trashRetention(retentionDays)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Error purging trash: %v", err)
+		case purged > 0:
+			log.Printf("Purged %d quote%s deleted more than %d days ago", purged, pluralSuffix(int(purged)), int(retention.Hours()/24))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
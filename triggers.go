@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommandTriggers decides which chat messages a channel treats as quote commands. The zero value
+// answers to "!quote" alone.
+type CommandTriggers struct {
+	// Prefix is the root command, e.g. "!quote"; Roots are further words that work the same way.
+	Prefix string
+	Roots  []string
+	// aliases maps extra words after the prefix to the subcommand they run.
+	aliases map[string]string
+	// shortcuts maps standalone triggers such as "!addquote" to the subcommand and leading
+	// arguments they stand for.
+	shortcuts map[string][]string
+	// disabled holds the subcommands that do not run in the channel.
+	disabled map[string]bool
+}
+
+// parseCommandTriggers validates a channel's trigger settings against the subcommands in registry.
+// Aliases, shortcut targets and disabled commands may name a subcommand by any of its aliases.
+func parseCommandTriggers(prefix string, roots []string, aliases, shortcuts map[string]string, disabled []string, registry *CommandRegistry) (CommandTriggers, error) {
+	t := CommandTriggers{
+		Prefix:    strings.ToLower(strings.TrimSpace(prefix)),
+		aliases:   make(map[string]string),
+		shortcuts: make(map[string][]string),
+		disabled:  make(map[string]bool),
+	}
+	if t.Prefix == "" {
+		t.Prefix = commandPrefix
+	}
+	triggers := map[string]bool{}
+	addTrigger := func(kind, word string) (string, error) {
+		word = strings.ToLower(strings.TrimSpace(word))
+		switch {
+		case word == "" || strings.ContainsAny(word, " \t"):
+			return "", fmt.Errorf("invalid %s %q (use a single word)", kind, word)
+		case triggers[word]:
+			return "", fmt.Errorf("%s %q is already used as a trigger", kind, word)
+		}
+		triggers[word] = true
+		return word, nil
+	}
+	if _, err := addTrigger("command prefix", t.Prefix); err != nil {
+		return CommandTriggers{}, err
+	}
+	for _, root := range roots {
+		root, err := addTrigger("command alias", root)
+		if err != nil {
+			return CommandTriggers{}, err
+		}
+		t.Roots = append(t.Roots, root)
+	}
+
+	for word, target := range aliases {
+		name, err := canonicalCommand(registry, "subcommand_aliases", target)
+		if err != nil {
+			return CommandTriggers{}, err
+		}
+		word = strings.ToLower(strings.TrimSpace(word))
+		if _, taken := registry.Lookup(word); taken || word == "" || strings.ContainsAny(word, " \t") {
+			return CommandTriggers{}, fmt.Errorf("subcommand_aliases: %q cannot be used as an alias", word)
+		}
+		t.aliases[word] = name
+	}
+	for trigger, expansion := range shortcuts {
+		trigger, err := addTrigger("command shortcut", trigger)
+		if err != nil {
+			return CommandTriggers{}, err
+		}
+		words := strings.Fields(expansion)
+		if len(words) == 0 {
+			return CommandTriggers{}, fmt.Errorf("command_shortcuts: %q needs a command to run", trigger)
+		}
+		if words[0], err = canonicalCommand(registry, "command_shortcuts", words[0]); err != nil {
+			return CommandTriggers{}, err
+		}
+		t.shortcuts[trigger] = words
+	}
+	for _, name := range disabled {
+		name, err := canonicalCommand(registry, "disabled_commands", name)
+		if err != nil {
+			return CommandTriggers{}, err
+		}
+		t.disabled[name] = true
+	}
+	return t, nil
+}
+
+// canonicalCommand returns the name of the subcommand that name, a name or alias in the setting
+// called setting, refers to.
+func canonicalCommand(registry *CommandRegistry, setting, name string) (string, error) {
+	cmd, ok := registry.Lookup(strings.TrimSpace(name))
+	if !ok {
+		return "", fmt.Errorf("%s: unknown command %q", setting, name)
+	}
+	return cmd.Info().Name, nil
+}
+
+// prefix returns the root command, falling back to "!quote" for the zero value.
+func (t CommandTriggers) prefix() string {
+	if t.Prefix == "" {
+		return commandPrefix
+	}
+	return t.Prefix
+}
+
+// match reports whether words, a chat message split on spaces, is a quote command. The first word
+// must be the prefix, a root alias or a shortcut exactly, so "!quotes" does not trigger "!quote".
+// It returns the subcommand named in the message, with aliases resolved, and the words after it;
+// the name is empty for the bare prefix.
+func (t CommandTriggers) match(words []string) (string, []string, bool) {
+	if len(words) == 0 {
+		return "", nil, false
+	}
+	first := strings.ToLower(words[0])
+	if expansion, ok := t.shortcuts[first]; ok {
+		return expansion[0], append(append([]string(nil), expansion[1:]...), words[1:]...), true
+	}
+	if first != t.prefix() && !containsString(t.Roots, first) {
+		return "", nil, false
+	}
+	if len(words) == 1 {
+		return "", nil, true
+	}
+	name := strings.ToLower(words[1])
+	if target, ok := t.aliases[name]; ok {
+		name = target
+	}
+	return name, words[2:], true
+}
+
+// Disabled reports whether the subcommand called name is turned off in the channel.
+func (t CommandTriggers) Disabled(name string) bool {
+	return t.disabled[name]
+}
+
+// aliasesFor returns the channel's own aliases and shortcuts for the subcommand called name.
+func (t CommandTriggers) aliasesFor(name string) []string {
+	var words []string
+	for word, target := range t.aliases {
+		if target == name {
+			words = append(words, word)
+		}
+	}
+	for trigger, expansion := range t.shortcuts {
+		if expansion[0] == name {
+			words = append(words, trigger)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
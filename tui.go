@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,8 +17,11 @@ import (
 
 type tuiApp struct {
 	app          *tview.Application
+	pages        *tview.Pages
 	config       AppConfig
+	display      DisplaySettings
 	status       *tview.TextView
+	queue        *tview.List
 	logView      *tview.TextView
 	header       *tview.TextView
 	footer       *tview.TextView
@@ -30,32 +34,41 @@ type tuiApp struct {
 	shortcutLine string
 
 	mu          sync.Mutex
-	store       *QuoteStore
-	lastCount   int
+	store       QuoteRepository
+	watching    bool
 	lastRefresh time.Time
+	pending     []PendingQuote
 	ctx         context.Context
 }
 
 // runTUI launches an interactive terminal UI for configuring and monitoring the quote bot.
 // It renders a form for Twitch/DB settings, persists updates to go-quote.config.json,
-// tails logs, and polls the database for health and activity changes.
-func runTUI(ctx context.Context, cfg AppConfig) error {
+// tails logs, logs every change to the primary channel's quotes as the store reports it, polls the
+// store for health, and lists the primary channel's approval queue so submissions can be approved
+// or rejected. store is the repository opened for cfg; it is replaced when a different DB path is
+// saved. display holds the time display resolved from cfg's channel settings.
+func runTUI(ctx context.Context, cfg AppConfig, store QuoteRepository, display DisplaySettings) error {
 	app := tview.NewApplication()
 	header := buildHeaderBar()
 	status := buildStatusView()
 	logView := buildLogView()
+	queue := buildQueueView()
+	pages := tview.NewPages()
 	shortcutLine := formatShortcutLine()
 	footer := buildFooterBar(shortcutLine)
 
 	tui := &tuiApp{
 		app:          app,
+		pages:        pages,
 		config:       cfg,
+		display:      display,
 		header:       header,
 		status:       status,
+		queue:        queue,
 		logView:      logView,
 		footer:       footer,
 		shortcutLine: shortcutLine,
-		lastCount:    -1,
+		store:        store,
 		ctx:          ctx,
 	}
 
@@ -64,10 +77,12 @@ func runTUI(ctx context.Context, cfg AppConfig) error {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
 	form := tui.buildForm()
+	queue.SetSelectedFunc(func(index int, _, _ string, _ rune) { tui.reviewPending(index) })
 
 	right := tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(status, 0, 1, false).
+		AddItem(queue, 0, 1, false).
 		AddItem(logView, 0, 2, false)
 
 	body := tview.NewGrid().
@@ -82,6 +97,7 @@ func runTUI(ctx context.Context, cfg AppConfig) error {
 		AddItem(header, 3, 0, false).
 		AddItem(body, 0, 1, true).
 		AddItem(footer, 2, 0, false)
+	pages.AddPage("main", root, true, true)
 
 	app.SetInputCapture(tui.captureKeys)
 
@@ -90,7 +106,7 @@ func runTUI(ctx context.Context, cfg AppConfig) error {
 	tui.renderHeader(cfg, -1, nil)
 	tui.flashFooter("Ready. Tab through fields, Ctrl+S to save, Ctrl+R to refresh.")
 
-	if err := app.SetRoot(root, true).EnableMouse(true).Run(); err != nil {
+	if err := app.SetRoot(pages, true).EnableMouse(true).Run(); err != nil {
 		return fmt.Errorf("running TUI: %w", err)
 	}
 	tui.closeStore()
@@ -114,6 +130,10 @@ func (t *tuiApp) captureKeys(event *tcell.EventKey) *tcell.EventKey {
 		t.app.SetFocus(t.logView)
 		t.flashFooter("Focused logs. Use PgUp/PgDn to scroll.")
 		return nil
+	case event.Key() == tcell.KeyCtrlP:
+		t.app.SetFocus(t.queue)
+		t.flashFooter("Focused approval queue. Enter to approve or reject.")
+		return nil
 	case event.Key() == tcell.KeyCtrlF:
 		t.app.SetFocus(t.modeDrop)
 		t.flashFooter("Focused form. Use Tab/Shift+Tab to move.")
@@ -170,6 +190,7 @@ func (t *tuiApp) buildForm() *tview.Form {
 		AddFormItem(t.channelField).
 		AddButton("Save config", t.saveConfig).
 		AddButton("Refresh health", func() { go t.refreshHealth() }).
+		AddButton("Backup now", func() { go t.backupNow() }).
 		AddButton("Quit", func() { t.app.Stop() })
 
 	form.SetBorder(true).SetTitle(" Setup ").SetTitleAlign(tview.AlignLeft)
@@ -188,28 +209,58 @@ func (t *tuiApp) saveConfig() {
 
 	t.mu.Lock()
 	t.config = cfg
-	t.lastCount = -1
 	t.closeStoreLocked()
 	t.mu.Unlock()
-	t.renderHeader(cfg, t.lastCount, nil)
+	t.renderHeader(cfg, -1, nil)
 
 	t.logf("Config saved to %s (mode=%s, channel=%s, db=%s)", configFileName, cfg.Mode, cfg.TwitchChannel, cfg.DBPath)
 	t.flashFooter("Config saved. Refreshing health...")
 	go t.refreshHealth()
 }
 
+// collectConfig returns the current config with the form fields applied, keeping settings the
+// form does not show.
 func (t *tuiApp) collectConfig() AppConfig {
 	_, mode := t.modeDrop.GetCurrentOption()
-	cfg := AppConfig{
-		Mode:          strings.ToLower(mode),
-		DBPath:        strings.TrimSpace(t.dbField.GetText()),
-		TwitchUser:    strings.TrimSpace(t.userField.GetText()),
-		TwitchOAuth:   strings.TrimSpace(t.oauthField.GetText()),
-		TwitchChannel: strings.TrimSpace(t.channelField.GetText()),
-	}
+	t.mu.Lock()
+	cfg := t.config
+	t.mu.Unlock()
+	cfg.Mode = strings.ToLower(mode)
+	cfg.DBPath = strings.TrimSpace(t.dbField.GetText())
+	cfg.TwitchUser = strings.TrimSpace(t.userField.GetText())
+	cfg.TwitchOAuth = strings.TrimSpace(t.oauthField.GetText())
+	cfg.TwitchChannel = strings.TrimSpace(t.channelField.GetText())
 	return cfg
 }
 
+// backupNow snapshots the SQLite database using the configured backup settings.
+func (t *tuiApp) backupNow() {
+	backupCtx, cancel := context.WithTimeout(t.ctx, time.Minute)
+	defer cancel()
+
+	cfg := t.collectConfig()
+	store, err := t.ensureStore(backupCtx, cfg.DBPath)
+	if err != nil {
+		t.logf("Backup failed: %v", err)
+		t.flashFooter("Backup failed. See logs.")
+		return
+	}
+	sqlite, ok := store.(*QuoteStore)
+	if !ok {
+		t.flashFooter("Backups only apply to the SQLite store.")
+		return
+	}
+	t.flashFooter("Backing up...")
+	info, err := backupAndRotate(backupCtx, sqlite, cfg.BackupSettings())
+	if err != nil {
+		t.logf("Backup failed: %v", err)
+		t.flashFooter("Backup failed. See logs.")
+		return
+	}
+	t.logf("Backed up database to %s (%d bytes, integrity ok)", info.Path, info.Size)
+	t.flashFooter("Backup written to " + info.Name())
+}
+
 func (t *tuiApp) refreshHealth() {
 	healthCtx, cancel := context.WithTimeout(t.ctx, 4*time.Second)
 	defer cancel()
@@ -221,21 +272,115 @@ func (t *tuiApp) refreshHealth() {
 		return
 	}
 
-	count, err := store.Count(healthCtx)
+	count, err := store.Count(healthCtx, cfg.PrimaryChannel())
 	if err != nil {
 		t.renderStatus(cfg, -1, nil, err)
 		return
 	}
 
 	var latest *Quote
-	if q, err := store.Latest(healthCtx); err == nil {
+	if q, err := store.Latest(healthCtx, cfg.PrimaryChannel()); err == nil {
 		latest = q
 	}
 
 	t.renderStatus(cfg, count, latest, nil)
-	t.detectChanges(count, latest)
+
+	pending, total, err := store.Pending(healthCtx, cfg.PrimaryChannel(), tuiQueueLimit)
+	if err != nil && !errors.Is(err, ErrNoQuotes) {
+		t.logf("Error listing pending quotes: %v", err)
+		return
+	}
+	t.renderQueue(cfg, pending, total)
+}
+
+// tuiQueueLimit is how many pending submissions the approval queue panel lists.
+const tuiQueueLimit = 50
+
+// renderQueue shows the submissions waiting in the primary channel's approval queue, keeping the
+// selection on the same row where possible.
+func (t *tuiApp) renderQueue(cfg AppConfig, pending []PendingQuote, total int) {
+	t.mu.Lock()
+	t.pending = pending
+	t.mu.Unlock()
+
+	now := time.Now()
+	t.app.QueueUpdateDraw(func() {
+		selected := t.queue.GetCurrentItem()
+		t.queue.Clear()
+		t.queue.SetTitle(fmt.Sprintf(" Approval queue #%s (%d) ", emptyPlaceholder(cfg.PrimaryChannel()), total))
+		for _, p := range pending {
+			main := fmt.Sprintf("#%d \"%s\" - %s", p.ID, truncate(p.Text, 60), p.Author)
+			secondary := fmt.Sprintf("  from %s via %s, %s", emptyPlaceholder(p.SubmittedBy), emptyPlaceholder(p.Platform), relativeTime(p.CreatedAt, now))
+			t.queue.AddItem(main, secondary, 0, nil)
+		}
+		if len(pending) > 0 {
+			t.queue.SetCurrentItem(min(selected, len(pending)-1))
+		}
+	})
 }
 
+// reviewPending asks whether to approve or reject the submission at index in the queue panel.
+func (t *tuiApp) reviewPending(index int) {
+	t.mu.Lock()
+	if index < 0 || index >= len(t.pending) {
+		t.mu.Unlock()
+		return
+	}
+	p := t.pending[index]
+	t.mu.Unlock()
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Pending #%d from %s:\n\n\"%s\" - %s", p.ID, emptyPlaceholder(p.SubmittedBy), p.Text, p.Author)).
+		AddButtons([]string{"Approve", "Reject", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			t.pages.RemovePage("review")
+			t.app.SetFocus(t.queue)
+			switch label {
+			case "Approve":
+				go t.reviewed(p, true)
+			case "Reject":
+				go t.reviewed(p, false)
+			}
+		})
+	t.pages.AddPage("review", modal, true, true)
+}
+
+// reviewed approves or rejects p on behalf of the TUI operator and refreshes the queue.
+func (t *tuiApp) reviewed(p PendingQuote, approve bool) {
+	ctx, cancel := context.WithTimeout(t.ctx, 4*time.Second)
+	defer cancel()
+
+	cfg := t.collectConfig()
+	store, err := t.ensureStore(ctx, cfg.DBPath)
+	if err != nil {
+		t.logf("Error opening store: %v", err)
+		t.flashFooter("Review failed. See logs.")
+		return
+	}
+	if approve {
+		added, err := store.Approve(ctx, p.Channel, p.ID, tuiActor)
+		if err != nil {
+			t.logf("Error approving pending #%d: %v", p.ID, err)
+			t.flashFooter("Approval failed. See logs.")
+			return
+		}
+		t.logf("Approved pending #%d as quote #%d", p.ID, added.ID)
+		t.flashFooter(fmt.Sprintf("Pending #%d approved as quote #%d.", p.ID, added.ID))
+	} else {
+		if err := store.Reject(ctx, p.Channel, p.ID, tuiActor, ""); err != nil {
+			t.logf("Error rejecting pending #%d: %v", p.ID, err)
+			t.flashFooter("Rejection failed. See logs.")
+			return
+		}
+		t.logf("Rejected pending #%d", p.ID)
+		t.flashFooter(fmt.Sprintf("Pending #%d rejected.", p.ID))
+	}
+	t.refreshHealth()
+}
+
+// tuiActor is recorded as the reviewer of submissions approved or rejected in the TUI.
+const tuiActor = "TUI"
+
 func (t *tuiApp) renderStatus(cfg AppConfig, count int, latest *Quote, err error) {
 	refreshedAt := time.Now()
 	t.mu.Lock()
@@ -257,12 +402,15 @@ func (t *tuiApp) renderStatus(cfg AppConfig, count int, latest *Quote, err error
 	} else {
 		sb.WriteString(fmt.Sprintf("[green]OK[-] • Quotes: %d\n", max(count, 0)))
 		if latest != nil {
-			sb.WriteString(fmt.Sprintf("Latest: #%d by %s at %s\n", latest.ID, latest.Author, latest.CreatedAt.Format(time.RFC822)))
+			sb.WriteString(fmt.Sprintf("Latest: #%d by %s, %s\n", latest.ID, latest.Author, t.display.For(cfg.PrimaryChannel()).Added(latest.CreatedAt, refreshedAt)))
+			if submitter := formatSubmitter(*latest); submitter != "" {
+				sb.WriteString(fmt.Sprintf("  %s in #%s\n", submitter, emptyPlaceholder(latest.Channel)))
+			}
 		} else {
 			sb.WriteString("No quotes stored yet.\n")
 		}
 	}
-	sb.WriteString(fmt.Sprintf("\nChecked at %s • auto refresh every 3s\n", refreshedAt.Format("15:04:05")))
+	sb.WriteString(fmt.Sprintf("\nChecked at %s • live updates, health check every 3s\n", refreshedAt.Format("15:04:05")))
 
 	text := sb.String()
 	t.app.QueueUpdateDraw(func() {
@@ -271,42 +419,85 @@ func (t *tuiApp) renderStatus(cfg AppConfig, count int, latest *Quote, err error
 	t.renderHeader(cfg, count, latest)
 }
 
-func (t *tuiApp) detectChanges(count int, latest *Quote) {
-	t.mu.Lock()
-	prev := t.lastCount
-	t.lastCount = count
-	t.mu.Unlock()
+// tuiChangeLogLimit is how many changes of a burst, such as a bulk import, are logged one by one.
+const tuiChangeLogLimit = 5
 
-	if prev >= 0 && count != prev {
-		t.logf("Quote count changed: %d -> %d", prev, count)
-		if latest != nil {
-			t.logf("Latest quote #%d \"%s\" - %s", latest.ID, truncate(latest.Text, 60), latest.Author)
+// watchChanges logs the changes store reports for channel and refreshes the status panel after
+// each burst of them. It returns when the store is closed.
+func (t *tuiApp) watchChanges(store QuoteRepository, channel string) {
+	events, err := store.Subscribe(t.ctx, channel)
+	if err != nil {
+		t.logf("Error subscribing to changes: %v", err)
+		return
+	}
+	for ev := range events {
+		burst := []ChangeEvent{ev}
+	drain:
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					break drain
+				}
+				burst = append(burst, ev)
+			default:
+				break drain
+			}
+		}
+		for _, ev := range burst[:min(len(burst), tuiChangeLogLimit)] {
+			t.logf("%s", formatChangeEvent(ev))
 		}
+		if extra := len(burst) - tuiChangeLogLimit; extra > 0 {
+			t.logf("...and %d more change%s", extra, pluralSuffix(extra))
+		}
+		t.refreshHealth()
+	}
+}
+
+// formatChangeEvent describes ev for the log panel.
+func formatChangeEvent(ev ChangeEvent) string {
+	verb := string(ev.Type)
+	switch ev.Action {
+	case RevisionAuthor:
+		verb = "re-attributed"
+	case RevisionRevert:
+		verb = "reverted"
+	case RevisionTag, RevisionUntag:
+		verb = "retagged"
 	}
+	return fmt.Sprintf("Quote #%d %s by %s: \"%s\" - %s", ev.QuoteID, verb, emptyPlaceholder(ev.Actor), truncate(ev.Text, 60), ev.Author)
 }
 
-func (t *tuiApp) ensureStore(ctx context.Context, dbPath string) (*QuoteStore, error) {
+func (t *tuiApp) ensureStore(ctx context.Context, dbPath string) (QuoteRepository, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	if t.store != nil && t.config.DBPath == dbPath {
+		t.watchLocked()
 		return t.store, nil
 	}
 
-	if t.store != nil {
-		t.store.Close()
-		t.store = nil
-	}
+	t.closeStoreLocked()
 
-	store, err := NewQuoteStore(ctx, dbPath)
+	store, err := openRepository(ctx, t.config.Store, dbPath, t.config.SQLiteSettings())
 	if err != nil {
 		return nil, err
 	}
 	t.store = store
 	t.config.DBPath = dbPath
+	t.watchLocked()
 	return store, nil
 }
 
+// watchLocked starts logging the current store's changes unless that is already happening. The
+// caller must hold t.mu.
+func (t *tuiApp) watchLocked() {
+	if !t.watching {
+		t.watching = true
+		go t.watchChanges(t.store, t.config.PrimaryChannel())
+	}
+}
+
 func (t *tuiApp) closeStore() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -317,6 +508,7 @@ func (t *tuiApp) closeStoreLocked() {
 	if t.store != nil {
 		_ = t.store.Close()
 		t.store = nil
+		t.watching = false
 	}
 }
 
@@ -376,6 +568,15 @@ func buildStatusView() *tview.TextView {
 	return view
 }
 
+func buildQueueView() *tview.List {
+	list := tview.NewList()
+	list.SetBorder(true)
+	list.SetTitle(" Approval queue ")
+	list.SetTitleAlign(tview.AlignLeft)
+	list.SetHighlightFullLine(true)
+	return list
+}
+
 func buildLogView() *tview.TextView {
 	view := tview.NewTextView()
 	view.SetDynamicColors(true)
@@ -421,6 +622,7 @@ func formatShortcutLine() string {
 		{"^S", "Save"},
 		{"^R", "Refresh"},
 		{"^L", "Focus Logs"},
+		{"^P", "Focus Queue"},
 		{"^F", "Focus Form"},
 		{"^Q", "Quit"},
 	}
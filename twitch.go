@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,7 +17,7 @@ import (
 type TwitchBot struct {
 	client        *twitch.Client
 	handler       *CommandHandler
-	channel       string
+	channels      []string
 	minRetryDelay time.Duration
 	maxRetryDelay time.Duration
 
@@ -26,13 +27,14 @@ type TwitchBot struct {
 	retryDelay time.Duration
 }
 
-// NewTwitchBot creates and configures a TwitchBot for the given IRC client, command handler, and channel.
+// NewTwitchBot creates and configures a TwitchBot for the given IRC client, command handler, and channels.
 // It initializes default retry delays and registers client event handlers for connect, reconnect, notice, and private messages so incoming messages are passed to the CommandHandler.
-func NewTwitchBot(client *twitch.Client, handler *CommandHandler, channel string) *TwitchBot {
+// Each message is handled in the scope of the channel it was sent in.
+func NewTwitchBot(client *twitch.Client, handler *CommandHandler, channels []string) *TwitchBot {
 	bot := &TwitchBot{
 		client:        client,
 		handler:       handler,
-		channel:       channel,
+		channels:      channels,
 		minRetryDelay: time.Second,
 		maxRetryDelay: 30 * time.Second,
 		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
@@ -40,12 +42,12 @@ func NewTwitchBot(client *twitch.Client, handler *CommandHandler, channel string
 	}
 
 	client.OnConnect(func() {
-		log.Printf("Connected to Twitch. Joining #%s", channel)
-		client.Join(channel)
+		log.Printf("Connected to Twitch. Joining #%s", strings.Join(channels, ", #"))
+		client.Join(channels...)
 		bot.resetRetryBackoff()
 	})
 	client.OnReconnectMessage(func(message twitch.ReconnectMessage) {
-		log.Printf("Twitch requested reconnect for channel #%s", channel)
+		log.Printf("Twitch requested reconnect for channel(s) #%s", strings.Join(channels, ", #"))
 		go func() {
 			if err := client.Disconnect(); err != nil && !errors.Is(err, twitch.ErrClientDisconnected) {
 				log.Printf("Error disconnecting Twitch client: %v", err)
@@ -66,7 +68,14 @@ func (b *TwitchBot) handleMessage(message twitch.PrivateMessage) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	responses := b.handler.Handle(ctx, message.Message, message.User.Name, isModerator(message.User))
+	sender := Sender{
+		Platform: PlatformTwitch,
+		ID:       message.User.ID,
+		Name:     firstNonEmpty(message.User.DisplayName, message.User.Name),
+		Login:    message.User.Name,
+		Role:     senderRole(message),
+	}
+	responses := b.handler.Handle(ctx, message.Channel, message.Message, sender)
 	for _, response := range responses {
 		b.client.Say(message.Channel, response)
 	}
@@ -173,15 +182,23 @@ func validateTwitchConfig(user, oauth, channel string) error {
 	return nil
 }
 
-func isModerator(user twitch.User) bool {
-	if user.Badges == nil {
-		return false
-	}
-	if _, ok := user.Badges["broadcaster"]; ok {
-		return true
+// senderRole derives a chatter's role from the badges and tags Twitch sends with their message.
+// Follower status is not among them and is looked up separately when a subcommand needs it.
+func senderRole(message twitch.PrivateMessage) Role {
+	hasBadge := func(badge string) bool {
+		_, ok := message.User.Badges[badge]
+		return ok
 	}
-	if _, ok := user.Badges["moderator"]; ok {
-		return true
+	switch {
+	case hasBadge("broadcaster"):
+		return RoleBroadcaster
+	case hasBadge("moderator") || message.Tags["mod"] == "1":
+		return RoleModerator
+	case hasBadge("vip") || message.Tags["vip"] == "1":
+		return RoleVIP
+	case hasBadge("subscriber") || hasBadge("founder") || message.Tags["subscriber"] == "1":
+		return RoleSubscriber
+	default:
+		return RoleEveryone
 	}
-	return false
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VoteResult reports the effect of a vote. Previous is the voter's earlier vote on the quote (0 if
+// none); Changed is false when the voter repeated the vote they had already cast.
+type VoteResult struct {
+	Score    int
+	Previous int
+	Changed  bool
+}
+
+// voterKey identifies a voter across name changes: the platform user ID when there is one, else the
+// lower-cased name, prefixed by the platform.
+func voterKey(sender Sender) string {
+	id := strings.TrimSpace(sender.ID)
+	if id == "" {
+		id = strings.ToLower(strings.TrimSpace(sender.Name))
+	}
+	if id == "" {
+		return ""
+	}
+	return sender.Platform + ":" + id
+}
+
+// validateVote checks the voter and vote value shared by every backend.
+func validateVote(voter string, value int) error {
+	if strings.TrimSpace(voter) == "" {
+		return fmt.Errorf("voter cannot be empty")
+	}
+	if value != 1 && value != -1 {
+		return fmt.Errorf("vote must be +1 or -1")
+	}
+	return nil
+}
+
+// Vote records voter's +1 or -1 on the live quote numbered id in channel, replacing any earlier vote
+// by the same voter, and updates the quote's score.
+func (s *QuoteStore) Vote(ctx context.Context, channel string, id int, voter string, value int) (VoteResult, error) {
+	if err := validateVote(voter, value); err != nil {
+		return VoteResult{}, err
+	}
+	var result VoteResult
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		q, err := liveQuoteTx(ctx, tx, channel, id)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRowContext(ctx, "SELECT value FROM quote_votes WHERE quote_id = ? AND voter = ?", q.RowID, voter).Scan(&result.Previous)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("fetching vote: %w", err)
+		}
+		result.Score = q.Score
+		if result.Previous == value {
+			return nil
+		}
+		result.Changed = true
+		const upsert = `INSERT INTO quote_votes(quote_id, voter, value, created_at) VALUES(?, ?, ?, ?)
+                ON CONFLICT(quote_id, voter) DO UPDATE SET value = excluded.value, created_at = excluded.created_at`
+		if _, err := tx.ExecContext(ctx, upsert, q.RowID, voter, value, formatSQLiteTime(time.Now())); err != nil {
+			return fmt.Errorf("recording vote: %w", err)
+		}
+		result.Score = q.Score - result.Previous + value
+		if _, err := tx.ExecContext(ctx, "UPDATE quotes SET score = ? WHERE id = ?", result.Score, q.RowID); err != nil {
+			return fmt.Errorf("updating score: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return VoteResult{}, err
+	}
+	return result, nil
+}
+
+// scoreWeight is how much a quote's score counts for RandomWeightScore: one extra share per net
+// upvote, and a proportionally smaller share for quotes voted down.
+func scoreWeight(score int) float64 {
+	if score >= 0 {
+		return 1 + float64(score)
+	}
+	return 1 / (1 - float64(score))
+}
+
+// formatScore renders a score with its sign, e.g. "+3", "0" or "-2".
+func formatScore(score int) string {
+	if score > 0 {
+		return fmt.Sprintf("+%d", score)
+	}
+	return fmt.Sprintf("%d", score)
+}